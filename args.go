@@ -1,19 +1,48 @@
 package ghostdoc
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/npolar/ghostdoc/context"
 )
 
+const (
+	pipePeekSize = 64 * 1024
+)
+
+// urlClient fetches remote input. The body is streamed to the parser so only
+// connecting and waiting for the response headers are limited in time
+var urlClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+	},
+}
+
 type rawFile struct {
-	name string
-	data []byte
+	name     string
+	path     string
+	sha256   string
+	checksum bool
+	reader   io.ReadCloser
 }
 
 // ArgumentHandler typdef
@@ -34,7 +63,7 @@ func NewArgumentHandler(parser *Parser, rawChan chan *rawFile) *ArgumentHandler
 
 // HasArgs checks if any commandline arguments where provided
 func (a *ArgumentHandler) hasArgs() (bool, error) {
-	if len(a.context.Args()) == 0 && !a.hasPipe() {
+	if len(a.context.Args()) == 0 && !a.hasPipe() && a.context.GlobalString("from-list") == "" {
 		// Should actually never happen...
 		return false, errors.New("[Argument Error] Called without arguments: " + a.context.Cli().App.Name + " -h for usage info.")
 	}
@@ -45,10 +74,11 @@ func (a *ArgumentHandler) hasArgs() (bool, error) {
 // ProcessArguments loops through all arguments and calls input handling
 func (a *ArgumentHandler) processArguments() {
 	go func() {
-		bytes, _ := ioutil.ReadAll(os.Stdin)
-		if bytes != nil && len(bytes) > 0 {
-			log.Info("Start with pipe")
-			a.handleInput(string(bytes))
+		if list := a.context.GlobalString("from-list"); list != "" {
+			log.WithFields(log.Fields{"list": list}).Info("Start with input list")
+			a.processList(list)
+		} else if a.hasPipe() && a.handlePipe(bufio.NewReaderSize(os.Stdin, pipePeekSize)) {
+			log.Info("Done with pipe")
 		} else {
 			log.WithFields(log.Fields{"args": a.context.Args()}).Info("Start with arguments")
 			for _, argument := range a.context.Args() {
@@ -59,17 +89,97 @@ func (a *ArgumentHandler) processArguments() {
 	}()
 }
 
+// processList reads input paths from the list file (or stdin when "-") and
+// feeds them to handleDiskInput one by one. Paths are newline separated, or
+// null separated when the null flag is set (find -print0)
+func (a *ArgumentHandler) processList(list string) {
+	var reader io.Reader = os.Stdin
+	if list != "-" {
+		file, err := os.Open(list)
+		if err != nil {
+			log.WithFields(log.Fields{"list": list}).Error("[Argument Error] ", err)
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	scanner := bufio.NewScanner(reader)
+	if a.context.GlobalBool("null") {
+		scanner.Split(a.scanNull)
+	}
+
+	for scanner.Scan() {
+		if path := a.trimListEntry(scanner.Text()); path != "" {
+			a.handleDiskInput(path, false)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.WithFields(log.Fields{"list": list}).Error("[Argument Error] ", err)
+	}
+}
+
+// scanNull is a bufio.SplitFunc splitting on null bytes
+func (a *ArgumentHandler) scanNull(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// trimListEntry strips the carriage return from windows style lists.
+// Null separated entries are used as is since they can contain any char
+func (a *ArgumentHandler) trimListEntry(entry string) string {
+	if a.context.GlobalBool("null") {
+		return entry
+	}
+	return strings.TrimSuffix(entry, "\r")
+}
+
 func (a *ArgumentHandler) hasPipe() bool {
 	fi, _ := os.Stdin.Stat()
 	return (fi.Mode()&os.ModeCharDevice == 0)
 }
 
+// handlePipe streams raw data piped via stdin to the parser. The start of the
+// pipe is used to detect raw data, anything else is handled as an argument.
+// Returns false when nothing was piped
+func (a *ArgumentHandler) handlePipe(stdin *bufio.Reader) bool {
+	peek, _ := stdin.Peek(pipePeekSize)
+	if len(peek) == 0 {
+		return false
+	}
+
+	log.Info("Start with pipe")
+	if a.parser.isRawInput(string(peek)) {
+		log.Info("Parsing raw input")
+		a.rawChan <- &rawFile{
+			name:   a.context.GlobalString("filename"),
+			reader: ioutil.NopCloser(stdin),
+		}
+	} else if argument, err := ioutil.ReadAll(stdin); err == nil {
+		a.handleInput(string(argument))
+	} else {
+		log.Error("[Input Error] ", err)
+	}
+	return true
+}
+
 func (a *ArgumentHandler) handleInput(argument string) {
-	if a.parser.isRawInput(argument) {
+	if a.isURL(argument) {
+		a.handleURLInput(argument)
+	} else if a.parser.isRawInput(argument) {
 		log.Info("Parsing raw input")
 		data := &rawFile{
-			name: a.context.GlobalString("filename"),
-			data: []byte(argument),
+			name:   a.context.GlobalString("filename"),
+			reader: ioutil.NopCloser(strings.NewReader(argument)),
 		}
 		a.rawChan <- data
 	} else {
@@ -102,11 +212,15 @@ func (a *ArgumentHandler) globDir(input string) {
 }
 
 func (a *ArgumentHandler) handleFileInput(input string) {
-	if raw, err := ioutil.ReadFile(input); err == nil {
+	if file, err := os.Open(input); err == nil {
 		log.Info("Parsing ", input)
 		data := &rawFile{
-			name: input,
-			data: raw,
+			name:   input,
+			reader: file,
+		}
+		if a.context.GlobalString("provenance") != "" {
+			data.path, _ = filepath.Abs(input)
+			data.checksum = true
 		}
 		a.rawChan <- data
 	} else {
@@ -114,6 +228,62 @@ func (a *ArgumentHandler) handleFileInput(input string) {
 	}
 }
 
+// isURL returns true if the argument is an absolute http(s) url
+func (a *ArgumentHandler) isURL(argument string) bool {
+	uri, err := url.Parse(argument)
+	return err == nil && (uri.Scheme == "http" || uri.Scheme == "https") && uri.Host != ""
+}
+
+// handleURLInput fetches the argument over http and streams the response body
+// as a rawFile when either the filename or the content type is supported
+func (a *ArgumentHandler) handleURLInput(input string) {
+	resp, err := urlClient.Get(input)
+	if err != nil {
+		log.WithFields(log.Fields{"input": input}).Error("[HTTP Error] ", err)
+		return
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		log.WithFields(log.Fields{"input": input}).Error("[HTTP Error] ", fmt.Sprintf("unexpected response: %s", resp.Status))
+		return
+	}
+
+	name := a.urlFileName(resp)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	if !a.parser.isSupportedFile(name) && !a.parser.isSupportedMediaType(mediaType) {
+		resp.Body.Close()
+		log.WithFields(log.Fields{"content-type": mediaType}).Warn("[Input Error] Unsupported content, skipping:", input)
+		return
+	}
+
+	log.Info("Parsing ", input)
+	data := &rawFile{
+		name:   name,
+		reader: resp.Body,
+	}
+	if a.context.GlobalString("provenance") != "" {
+		data.path = redactURL(input)
+	}
+	a.rawChan <- data
+}
+
+// urlFileName uses the filename from the Content-Disposition header if
+// present and falls back on the last element of the url path, or the host
+// for urls without a path
+func (a *ArgumentHandler) urlFileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := params["filename"]; name != "" {
+			return path.Base(name)
+		}
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return resp.Request.URL.Hostname()
+}
+
 func (a *ArgumentHandler) configuration(input string) bool {
 	configuration := false
 
@@ -0,0 +1,90 @@
+package ghostdoc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleURLInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		disposition string
+		want        string
+	}{
+		{"file name from the url", "/data/docs.json", http.StatusOK, "", "", "docs.json"},
+		{"supported content type", "/api/docs", http.StatusOK, "application/json; charset=utf-8", "", "docs"},
+		{"host name for the root path", "/", http.StatusOK, "application/json", "", "127.0.0.1"},
+		{"file name from the content disposition", "/api/export", http.StatusOK, "application/octet-stream", `attachment; filename="../export.json"`, "export.json"},
+		{"unsupported content", "/api/docs", http.StatusOK, "text/html", "", ""},
+		{"not found", "/data/docs.json", http.StatusNotFound, "application/json", "", ""},
+		{"server error", "/data/docs.json", http.StatusInternalServerError, "application/json", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != test.path {
+					t.Errorf("requested %s, want %s", r.URL.Path, test.path)
+				}
+				if test.contentType != "" {
+					w.Header().Set("Content-Type", test.contentType)
+				}
+				if test.disposition != "" {
+					w.Header().Set("Content-Disposition", test.disposition)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(`{"a": 1}`))
+			}))
+			defer server.Close()
+
+			c := newTestContext(map[string]interface{}{"provenance": "_provenance"})
+			rawChan := make(chan *rawFile, 1)
+			a := &ArgumentHandler{context: c, rawChan: rawChan, parser: &Parser{context: c, ParserStrategy: NewJSONStrategy(c)}}
+			a.handleURLInput(server.URL + test.path)
+			close(rawChan)
+
+			raw := <-rawChan
+			switch {
+			case test.want == "" && raw != nil:
+				t.Errorf("got %s, want the input skipped", raw.name)
+			case test.want != "" && raw == nil:
+				t.Errorf("input skipped, want %s", test.want)
+			case raw != nil:
+				defer raw.reader.Close()
+				if raw.name != test.want {
+					t.Errorf("name %q, want %q", raw.name, test.want)
+				}
+				if raw.path != server.URL+test.path {
+					t.Errorf("path %q, want %q", raw.path, server.URL+test.path)
+				}
+				if body, _ := ioutil.ReadAll(raw.reader); string(body) != `{"a": 1}` {
+					t.Errorf("body %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"https://api.example.org/docs", true},
+		{"http://localhost:8080", true},
+		{"ftp://example.org/docs.json", false},
+		{"/data/docs.json", false},
+		{`{"a": "http://example.org"}`, false},
+	}
+
+	for _, test := range tests {
+		if got := (&ArgumentHandler{}).isURL(test.in); got != test.want {
+			t.Errorf("isURL(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OverlayContext serves flag values from a configuration map and falls back
+// on the wrapped GhostContext for everything that is not configured. Local
+// and global lookups read the same map.
+type OverlayContext struct {
+	GhostContext
+	values map[string]interface{}
+}
+
+// NewOverlayContext instantiates a new OverlayContext
+func NewOverlayContext(c GhostContext, values map[string]interface{}) *OverlayContext {
+	return &OverlayContext{GhostContext: c, values: values}
+}
+
+// Int @see cli.Context.Int
+func (c *OverlayContext) Int(name string) int {
+	if val, ok := c.values[name]; ok {
+		return c.toInt(val)
+	}
+	return c.GhostContext.Int(name)
+}
+
+// Bool @see cli.Context.Bool
+func (c *OverlayContext) Bool(name string) bool {
+	if val, ok := c.values[name]; ok {
+		return c.toBool(val)
+	}
+	return c.GhostContext.Bool(name)
+}
+
+// String @see cli.Context.String
+func (c *OverlayContext) String(name string) string {
+	if val, ok := c.values[name]; ok {
+		return c.toString(val)
+	}
+	return c.GhostContext.String(name)
+}
+
+// GlobalInt @see cli.Context.GlobalInt
+func (c *OverlayContext) GlobalInt(name string) int {
+	if val, ok := c.values[name]; ok {
+		return c.toInt(val)
+	}
+	return c.GhostContext.GlobalInt(name)
+}
+
+// GlobalBool @see cli.Context.GlobalBool
+func (c *OverlayContext) GlobalBool(name string) bool {
+	if val, ok := c.values[name]; ok {
+		return c.toBool(val)
+	}
+	return c.GhostContext.GlobalBool(name)
+}
+
+// GlobalString @see cli.Context.GlobalString
+func (c *OverlayContext) GlobalString(name string) string {
+	if val, ok := c.values[name]; ok {
+		return c.toString(val)
+	}
+	return c.GhostContext.GlobalString(name)
+}
+
+// IsSet @see cli.Context.IsSet
+func (c *OverlayContext) IsSet(name string) bool {
+	_, ok := c.values[name]
+	return ok || c.GhostContext.IsSet(name)
+}
+
+// GlobalIsSet @see cli.Context.GlobalIsSet
+func (c *OverlayContext) GlobalIsSet(name string) bool {
+	_, ok := c.values[name]
+	return ok || c.GhostContext.GlobalIsSet(name)
+}
+
+// toString converts configured values to their flag representation. Lists of
+// values are comma separated and objects are serialized as JSON
+func (c *OverlayContext) toString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, c.toString(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (c *OverlayContext) toInt(val interface{}) int {
+	switch v := val.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		i, _ := strconv.Atoi(c.toString(v))
+		return i
+	}
+}
+
+func (c *OverlayContext) toBool(val interface{}) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	default:
+		b, _ := strconv.ParseBool(c.toString(v))
+		return b
+	}
+}
@@ -0,0 +1,41 @@
+package context
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/codegangsta/cli"
+)
+
+func TestOverlayContext(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("delimiter", ",", "")
+	set.Int("limit", 10, "")
+	set.Parse([]string{"--delimiter", ";"})
+	c := NewOverlayContext(NewCliContext(cli.NewContext(nil, set, nil)), map[string]interface{}{
+		"list":   []interface{}{"a", 1.0, true},
+		"object": map[string]interface{}{"key": "id"},
+		"count":  4.0,
+		"text":   "5",
+		"strict": "true",
+	})
+
+	if got := c.String("list"); got != "a,1,true" {
+		t.Errorf("String(list) = %q", got)
+	}
+	if got := c.GlobalString("object"); got != `{"key":"id"}` {
+		t.Errorf("GlobalString(object) = %q", got)
+	}
+	if got := c.String("delimiter"); got != ";" {
+		t.Errorf("String(delimiter) = %q, want the flag", got)
+	}
+	if c.Int("count") != 4 || c.Int("text") != 5 || c.Int("limit") != 10 {
+		t.Errorf("Int = %d, %d, %d, want 4, 5, 10", c.Int("count"), c.Int("text"), c.Int("limit"))
+	}
+	if !c.Bool("strict") || c.GlobalBool("unknown") {
+		t.Error("Bool(strict) should be true and unknown flags false")
+	}
+	if !c.IsSet("count") || !c.IsSet("delimiter") || c.IsSet("limit") {
+		t.Error("IsSet should hold for overlay values and flags set on the command line")
+	}
+}
@@ -29,13 +29,28 @@ func CsvCommand() cli.Command {
 				Name:  "skip, s",
 				Usage: "Specify the number of lines to skip before parsing. [NOTE] Blank lines are ignored by the parser and should not be skipped.",
 			},
+			cli.BoolFlag{
+				Name:  "decimal-comma, dc",
+				Usage: "Numbers use a decimal comma (12,5)",
+			},
+			cli.StringFlag{
+				Name:  "thousands, ts",
+				Usage: "Thousands separator chars in numbers, e.g. \" \" or \".\" (1 234,5)",
+			},
+			cli.BoolFlag{
+				Name:  "unicode-minus, um",
+				Usage: "Read the unicode minus sign (−) as a minus",
+			},
 		},
 		Action: processCsv,
 	}
 }
 
 func processCsv(c *cli.Context) {
-	csvStrategy := NewCsvStrategy(context.NewCliContext(c))
+	csvStrategy, err := NewCsvStrategy(context.NewCliContext(c))
+	if err != nil {
+		panic(err.Error())
+	}
 	parser := NewParser(csvStrategy)
 	parser.process()
 }
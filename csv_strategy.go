@@ -1,6 +1,11 @@
 package ghostdoc
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"regexp"
 	"strconv"
@@ -15,15 +20,32 @@ import (
 type CsvStrategy struct {
 	context   context.GhostContext
 	delimiter string
+	decoder   *Decoder
+	locale    *numberLocale
 }
 
 const (
-	csvExtRegex = `(?i)^.+\.csv|tsv|txt`
+	csvExtRegex   = `(?i)^.+\.csv|tsv|txt`
+	csvMediaRegex = `(?i)^text/(csv|tab-separated-values|plain)$`
+	csvBatchSize  = 512
 )
 
 // NewCsvStrategy factory
-func NewCsvStrategy(context context.GhostContext) *CsvStrategy {
-	return &CsvStrategy{context: context, delimiter: context.String("delimiter")}
+func NewCsvStrategy(context context.GhostContext) (*CsvStrategy, error) {
+	decoder, err := NewDecoder(context)
+	if err != nil {
+		return nil, err
+	}
+	locale, err := newNumberLocale(context)
+	if err != nil {
+		return nil, err
+	}
+	return &CsvStrategy{
+		context:   context,
+		delimiter: context.String("delimiter"),
+		decoder:   decoder,
+		locale:    locale,
+	}, nil
 }
 
 // rawInput does a lazy check for raw inline input and returns true if matches
@@ -38,40 +60,223 @@ func (c *CsvStrategy) isSupportedFile(filename string) bool {
 	return csvFile.MatchString(filename)
 }
 
+// isSupportedMediaType returns true if the content type of a remote input is supported
+func (c *CsvStrategy) isSupportedMediaType(mediaType string) bool {
+	csvMedia := regexp.MustCompile(csvMediaRegex)
+	return csvMedia.MatchString(mediaType)
+}
+
 func (c *CsvStrategy) getContext() context.GhostContext {
 	return c.context
 }
 
+// parse streams the csv records in batches to ciface. Only one batch is held
+// in memory at a time, independent of the input size. The types ciface infers
+// in the first batch are kept for the rest of the file
 func (c *CsvStrategy) parse(rawFile *rawFile, dataChan chan *dataFile) {
-	cif := ciface.NewParser(rawFile.data)
-	cif.Skip = c.context.Int("skip")
+	reader := c.csvReader(rawFile.reader)
+	header, err := c.readHeader(reader)
+	types := make(columnTypes)
+
+	for err == nil {
+		var records [][]string
+		var lines []int
+		if records, lines, err = c.readBatch(rawFile, reader, len(header)); len(records) > 0 {
+			c.parseBatch(rawFile, header, records, lines, types, dataChan)
+		}
+	}
+
+	if err != io.EOF {
+		log.Error("[Parsing error]", err)
+	}
+}
+
+// csvReader creates a reader for the input configured like the ciface reader.
+// The number of fields is checked per record by readBatch
+func (c *CsvStrategy) csvReader(input io.Reader) *csv.Reader {
+	template := ciface.NewParser(nil).Reader
+	reader := csv.NewReader(input)
+	reader.Comma = c.delimiterRune()
+	reader.Comment = c.commentRune()
+	reader.LazyQuotes = template.LazyQuotes
+	reader.TrimLeadingSpace = template.TrimLeadingSpace
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// readHeader skips the configured number of records and returns the
+// configured header, or the first record when no header is set
+func (c *CsvStrategy) readHeader(reader *csv.Reader) ([]string, error) {
+	for i := 0; i < c.context.Int("skip"); i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, err
+		}
+	}
 
 	if header := c.context.String("header"); header != "" {
 		hfile, err := ioutil.ReadFile(header)
 		if err != nil {
-			cif.Header = util.StringToSlice(header)
-		} else {
-			cif.Header = util.StringToSlice(string(hfile))
+			return util.StringToSlice(header), nil
 		}
+		if hfile, err = c.decoder.decodeBytes(hfile); err != nil {
+			return nil, errors.New("[Header error] " + err.Error())
+		}
+		return util.StringToSlice(string(hfile)), nil
 	}
 
-	delimiterRune, _, _, _ := strconv.UnquoteChar(c.context.String("delimiter"), '"')
-	cif.Reader.Comma = delimiterRune
+	return reader.Read()
+}
 
-	commentRune, _, _, _ := strconv.UnquoteChar(c.context.String("comment"), '"')
-	cif.Reader.Comment = commentRune
+// readBatch reads the next batch of records, with locale numbers rewritten so
+// the type inference picks them up. Plain numbers that do not match the number
+// format are logged as ambiguous. The input line of every record is returned
+// for the provenance. Records that can not be read or do not have a field for
+// every column are logged and skipped
+func (c *CsvStrategy) readBatch(rawFile *rawFile, reader *csv.Reader, fields int) ([][]string, []int, error) {
+	var err error
+	var records [][]string
+	var lines []int
+
+	for len(records) < csvBatchSize && err == nil {
+		var record []string
+		record, err = reader.Read()
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			log.WithFields(log.Fields{"file": rawFile.name, "line": parseErr.Line}).Warn("[Parsing error] Skipping record: ", parseErr.Err)
+			err = nil
+			continue
+		}
+		if err != nil {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != fields {
+			log.WithFields(log.Fields{"file": rawFile.name, "line": line}).Warn(fmt.Sprintf("[Parsing error] Skipping record with %d fields, expected %d", len(record), fields))
+			continue
+		}
+
+		if c.locale != nil {
+			for _, i := range c.locale.normalizeRecord(record) {
+				log.WithFields(log.Fields{"file": rawFile.name, "line": line, "column": i + 1}).Warn("[Parsing error] Ambiguous number for the number format: ", record[i])
+			}
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return records, lines, err
+}
+
+// parseBatch writes the records back to csv, parses them with ciface and pushes
+// the docs onto the data channel. Comments are already removed by the stream reader
+func (c *CsvStrategy) parseBatch(rawFile *rawFile, header []string, records [][]string, lines []int, types columnTypes, dataChan chan *dataFile) {
+	var batch bytes.Buffer
+	writer := csv.NewWriter(&batch)
+	writer.Comma = c.delimiterRune()
+	writer.WriteAll(records)
+
+	cif := ciface.NewParser(batch.Bytes())
+	cif.Header = header
+	cif.Reader.Comma = c.delimiterRune()
+	cif.Reader.Comment = 0
 
 	docs, err := cif.Parse()
+	if len(docs) == len(records) {
+		types.fix(docs)
+		for i, doc := range docs {
+			types.apply(rawFile, header, records[i], lines[i], doc.(map[string]interface{}))
+		}
+	}
 
-	// push the docs onto the data channel
-	for _, doc := range docs {
-		dataChan <- &dataFile{
-			name: rawFile.name,
-			data: doc.(map[string]interface{}),
+	// push the docs onto the data channel, ciface returns one doc per record
+	for i, doc := range docs {
+		data := &dataFile{
+			name:   rawFile.name,
+			data:   doc.(map[string]interface{}),
+			source: rawFile,
 		}
+		if len(docs) == len(lines) {
+			data.row = lines[i]
+		}
+		dataChan <- data
+	}
+
+	if len(docs) != len(lines) && c.context.GlobalString("provenance") != "" {
+		log.WithFields(log.Fields{"file": rawFile.name, "records": len(lines), "documents": len(docs)}).Warn("[Provenance] Rows not recorded, the batch records and documents differ")
 	}
 
 	if err != nil {
 		log.Error("[Parsing error]", err)
 	}
 }
+
+// columnTypes holds the type of every csv column of a file: number, bool or
+// string. A column gets its type in the first batch where it has a value, a
+// column with values of different types is a string column
+type columnTypes map[string]string
+
+// fix sets the types of the columns that do not have one yet from the docs
+func (t columnTypes) fix(docs []interface{}) {
+	batch := make(map[string]string)
+	for _, doc := range docs {
+		for column, value := range doc.(map[string]interface{}) {
+			if _, fixed := t[column]; fixed {
+				continue
+			}
+			switch kind := valueType(value); {
+			case kind == "":
+			case batch[column] == "":
+				batch[column] = kind
+			case batch[column] != kind:
+				batch[column] = "string"
+			}
+		}
+	}
+	for column, kind := range batch {
+		t[column] = kind
+	}
+}
+
+// apply gives the values of a doc the type of their column. Numbers and bools
+// in a string column get the text of the record back, text in a number or
+// bool column is kept and logged
+func (t columnTypes) apply(rawFile *rawFile, header []string, record []string, line int, doc map[string]interface{}) {
+	for i, column := range header {
+		value, ok := doc[column]
+		kind := valueType(value)
+		if !ok || kind == "" || t[column] == "" || kind == t[column] {
+			continue
+		}
+		if t[column] == "string" {
+			doc[column] = record[i]
+			continue
+		}
+		log.WithFields(log.Fields{"file": rawFile.name, "line": line, "column": i + 1}).Warn("[Parsing error] Text in a "+t[column]+" column: ", value)
+	}
+}
+
+// valueType returns the column type of a parsed value, or "" for empty values
+func valueType(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if typed == "" {
+			return ""
+		}
+	case bool:
+		return "bool"
+	case float64, float32, int, int64:
+		return "number"
+	}
+	return "string"
+}
+
+func (c *CsvStrategy) delimiterRune() rune {
+	delimiterRune, _, _, _ := strconv.UnquoteChar(c.context.String("delimiter"), '"')
+	return delimiterRune
+}
+
+func (c *CsvStrategy) commentRune() rune {
+	commentRune, _, _, _ := strconv.UnquoteChar(c.context.String("comment"), '"')
+	return commentRune
+}
@@ -0,0 +1,138 @@
+package ghostdoc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCsvStrategyParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    int
+		ragged  []int
+		broken  []int
+		want    int
+		lastRow int
+	}{
+		{"single batch", 10, nil, nil, 10, 11},
+		{"several batches", 1300, nil, nil, 1300, 1301},
+		{"ragged rows are skipped", 1300, []int{5, 600, 1299}, nil, 1297, 1301},
+		{"unreadable rows are skipped", 700, nil, []int{513}, 699, 701},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var input strings.Builder
+			input.WriteString("id,value,name\n")
+			for i := 1; i <= test.rows; i++ {
+				switch {
+				case containsInt(test.ragged, i):
+					fmt.Fprintf(&input, "%d,%d\n", i, i*2)
+				case containsInt(test.broken, i):
+					fmt.Fprintf(&input, "%d,%d,a \"b\" c\n", i, i*2)
+				default:
+					fmt.Fprintf(&input, "%d,%d,row %d\n", i, i*2, i)
+				}
+			}
+
+			c, err := NewCsvStrategy(newTestContext(map[string]interface{}{"delimiter": ","}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw := &rawFile{name: "test.csv", reader: ioutil.NopCloser(strings.NewReader(input.String()))}
+			dataChan := make(chan *dataFile, test.rows)
+			c.parse(raw, dataChan)
+			close(dataChan)
+
+			count, last := 0, 0
+			for doc := range dataChan {
+				count++
+				if want := fmt.Sprintf("row %v", doc.data["id"]); doc.data["name"] != want {
+					t.Fatalf("line %d: name %v, want %s", doc.row, doc.data["name"], want)
+				}
+				if id, _ := toNumber(doc.data["id"]); int(id)+1 != doc.row {
+					t.Fatalf("id %v on line %d", doc.data["id"], doc.row)
+				}
+				last = doc.row
+			}
+			if count != test.want {
+				t.Errorf("got %d documents, want %d", count, test.want)
+			}
+			if last != test.lastRow {
+				t.Errorf("last document on line %d, want %d", last, test.lastRow)
+			}
+		})
+	}
+}
+
+func TestCsvStrategyColumnTypes(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,station,depth\n")
+	for i := 1; i <= 600; i++ {
+		station, depth := strconv.Itoa(i), ""
+		if i == 1 {
+			station = "N1"
+		}
+		if i > csvBatchSize {
+			depth = strconv.Itoa(i)
+		}
+		fmt.Fprintf(&input, "%d,%s,%s\n", i, station, depth)
+	}
+
+	c, err := NewCsvStrategy(newTestContext(map[string]interface{}{"delimiter": ","}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := &rawFile{name: "test.csv", reader: ioutil.NopCloser(strings.NewReader(input.String()))}
+	dataChan := make(chan *dataFile, 600)
+	c.parse(raw, dataChan)
+	close(dataChan)
+
+	for doc := range dataChan {
+		if _, ok := doc.data["id"].(float64); !ok {
+			t.Fatalf("line %d: id %#v is not a number", doc.row, doc.data["id"])
+		}
+		if _, ok := doc.data["station"].(string); !ok {
+			t.Fatalf("line %d: station %#v is not a string", doc.row, doc.data["station"])
+		}
+		if _, ok := doc.data["depth"].(float64); doc.row > csvBatchSize+1 && !ok {
+			t.Fatalf("line %d: depth %#v is not a number", doc.row, doc.data["depth"])
+		}
+	}
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNewCsvStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		err      bool
+	}{
+		{name: "no encoding"},
+		{name: "known encoding", encoding: "latin1"},
+		{name: "unknown encoding", encoding: "klingon", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := NewCsvStrategy(newTestContext(map[string]interface{}{"delimiter": ",", "encoding": test.encoding}))
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if !test.err && c.decoder == nil {
+				t.Error("expected a decoder")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+var (
+	// excelEpoch is day 0 of the Excel 1900 date system (including its leap year bug)
+	excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+	strptimeDirectives = map[byte]string{
+		'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'j': "002",
+		'H': "15", 'I': "03", 'M': "04", 'S': "05", 'f': "000000", 'p': "PM",
+		'b': "Jan", 'h': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+		'z': "-0700", 'Z': "MST", 'T': "15:04:05", 'F': "2006-01-02", 'D': "01/02/06",
+		'%': "%",
+	}
+)
+
+// dateField configures the parsing of a single date field
+type dateField struct {
+	Key       string      `json:"key"`
+	Time      string      `json:"time"`
+	Target    string      `json:"target"`
+	Format    string      `json:"format"`
+	InputZone string      `json:"input_zone"`
+	Year      interface{} `json:"year"`
+	Remove    bool        `json:"remove"`
+	layout    string
+	location  *time.Location
+}
+
+// dateSpec is the --dates configuration
+type dateSpec struct {
+	Zone      string       `json:"zone"`
+	InputZone string       `json:"input_zone"`
+	OnError   string       `json:"on_error"`
+	Fields    []*dateField `json:"fields"`
+}
+
+// DateNormalizer parses date fields in various formats and rewrites them as
+// RFC 3339 in the configured time zone. Supported formats are Go layouts,
+// strptime patterns (%Y-%m-%d), unix, unix_ms, excel and doy (decimal day of year)
+type DateNormalizer struct {
+	context  context.GhostContext
+	spec     dateSpec
+	location *time.Location
+	err      error
+}
+
+// NewDateNormalizer factory
+func NewDateNormalizer(c context.GhostContext) *DateNormalizer {
+	d := &DateNormalizer{context: c, location: time.UTC}
+	if spec := c.GlobalString("dates"); spec != "" {
+		if d.err = util.ReadConfig(spec, &d.spec); d.err == nil {
+			d.err = d.compile()
+		}
+	}
+	return d
+}
+
+// compile resolves the time zones and converts strptime patterns to Go layouts
+func (d *DateNormalizer) compile() error {
+	var err error
+	if d.spec.Zone != "" {
+		if d.location, err = time.LoadLocation(d.spec.Zone); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range d.spec.Fields {
+		if field.Key == "" || field.Format == "" {
+			return errors.New("Date fields need both key and format")
+		}
+
+		zone := field.InputZone
+		if zone == "" {
+			zone = d.spec.InputZone
+		}
+		if field.location, err = time.LoadLocation(zone); err != nil {
+			return fmt.Errorf("%s: %v", field.Key, err)
+		}
+
+		field.layout = field.Format
+		if strings.Contains(field.Format, "%") {
+			field.layout = d.strptimeLayout(field.Format)
+		}
+	}
+	return nil
+}
+
+func (d *DateNormalizer) normalize(data map[string]interface{}) (map[string]interface{}, error) {
+	if d.err != nil {
+		return data, errors.New("dates: " + d.err.Error())
+	}
+
+	for _, field := range d.spec.Fields {
+		value, ok := util.GetPath(data, field.Key)
+		if !ok || value == nil || value == "" {
+			continue
+		}
+
+		if field.Time != "" {
+			if timeValue, ok := util.GetPath(data, field.Time); ok {
+				value = fmt.Sprintf("%v %v", d.toString(value), d.toString(timeValue))
+			}
+		}
+
+		parsed, err := d.parse(field, value, data)
+		if err != nil {
+			err = fmt.Errorf("dates: %s: could not parse %q as %s: %v", field.Key, d.toString(value), field.Format, err)
+			if d.spec.OnError == "warn" {
+				log.Warn(err.Error())
+				continue
+			}
+			return data, err
+		}
+
+		var remove []string
+		target := field.Target
+		if target == "" {
+			target = field.Key
+		} else if field.Remove {
+			remove = append(remove, field.Key)
+		}
+		if field.Remove && field.Time != "" {
+			remove = append(remove, field.Time)
+		}
+		util.DeletePaths(data, remove)
+
+		if err = util.SetPath(data, target, parsed.In(d.location).Format(time.RFC3339Nano)); err != nil {
+			return data, errors.New("dates: " + err.Error())
+		}
+	}
+
+	return data, nil
+}
+
+func (d *DateNormalizer) parse(field *dateField, value interface{}, data map[string]interface{}) (time.Time, error) {
+	switch field.Format {
+	case "unix":
+		seconds, err := d.toFloat(value)
+		return d.fromSeconds(seconds), err
+	case "unix_ms":
+		millis, err := d.toFloat(value)
+		return d.fromSeconds(millis / 1000), err
+	case "excel":
+		days, err := d.toFloat(value)
+		return excelEpoch.Add(d.days(days)), err
+	case "doy":
+		return d.dayOfYear(field, value, data)
+	default:
+		return time.ParseInLocation(field.layout, strings.TrimSpace(d.toString(value)), field.location)
+	}
+}
+
+// dayOfYear parses a (decimal) day of year where January 1st 00:00 is day 1.
+// The year is either a number or a key path to the year field
+func (d *DateNormalizer) dayOfYear(field *dateField, value interface{}, data map[string]interface{}) (time.Time, error) {
+	day, err := d.toFloat(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	year := field.Year
+	if key, ok := year.(string); ok {
+		if year, ok = util.GetPath(data, key); !ok {
+			return time.Time{}, errors.New("missing year field " + key)
+		}
+	}
+
+	y, err := d.toFloat(year)
+	if err != nil {
+		return time.Time{}, errors.New("invalid year")
+	}
+
+	start := time.Date(int(y), time.January, 1, 0, 0, 0, 0, field.location)
+	return start.Add(d.days(day - 1)), nil
+}
+
+func (d *DateNormalizer) fromSeconds(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+func (d *DateNormalizer) days(days float64) time.Duration {
+	return time.Duration(math.Round(days * 24 * float64(time.Hour)))
+}
+
+// strptimeLayout converts a strptime pattern to a Go layout
+func (d *DateNormalizer) strptimeLayout(format string) string {
+	var layout strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			if directive, ok := strptimeDirectives[format[i+1]]; ok {
+				layout.WriteString(directive)
+				i++
+				continue
+			}
+		}
+		layout.WriteByte(format[i])
+	}
+	return layout.String()
+}
+
+func (d *DateNormalizer) toString(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func (d *DateNormalizer) toFloat(value interface{}) (float64, error) {
+	if number, ok := value.(float64); ok {
+		return number, nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(d.toString(value)), 64)
+}
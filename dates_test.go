@@ -0,0 +1,63 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDateNormalizer(t *testing.T) {
+	tests := []struct {
+		spec string
+		in   map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			`{"fields": [{"key": "t", "format": "02.01.2006 15:04", "input_zone": "Europe/Oslo"}]}`,
+			map[string]interface{}{"t": "01.03.2016 12:00"},
+			map[string]interface{}{"t": "2016-03-01T11:00:00Z"},
+		},
+		{
+			`{"fields": [{"key": "date", "time": "time", "format": "%Y-%m-%d %H:%M", "target": "t", "remove": true}]}`,
+			map[string]interface{}{"date": "2016-03-01", "time": "12:30"},
+			map[string]interface{}{"t": "2016-03-01T12:30:00Z"},
+		},
+		{
+			`{"zone": "Europe/Oslo", "fields": [{"key": "t", "format": "unix"}]}`,
+			map[string]interface{}{"t": 1456833600.0},
+			map[string]interface{}{"t": "2016-03-01T13:00:00+01:00"},
+		},
+		{
+			`{"fields": [{"key": "t", "format": "excel"}]}`,
+			map[string]interface{}{"t": 42430.5},
+			map[string]interface{}{"t": "2016-03-01T12:00:00Z"},
+		},
+		{
+			`{"fields": [{"key": "t", "format": "doy", "year": "y"}]}`,
+			map[string]interface{}{"t": 61.5, "y": 2016.0},
+			map[string]interface{}{"t": "2016-03-01T12:00:00Z", "y": 2016.0},
+		},
+		{
+			`{"on_error": "warn", "fields": [{"key": "t", "format": "2006-01-02"}]}`,
+			map[string]interface{}{"t": "01.03.2016"},
+			map[string]interface{}{"t": "01.03.2016"},
+		},
+	}
+
+	for _, test := range tests {
+		d := NewDateNormalizer(newTestContext(map[string]interface{}{"dates": test.spec}))
+		if d.err != nil {
+			t.Fatal(d.err)
+		}
+		if got, err := d.normalize(test.in); err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, %v, want %v", test.spec, got, err, test.want)
+		}
+	}
+
+	d := NewDateNormalizer(newTestContext(map[string]interface{}{"dates": `{"fields": [{"key": "t", "format": "2006-01-02"}]}`}))
+	if _, err := d.normalize(map[string]interface{}{"t": "01.03.2016"}); err == nil {
+		t.Error("expected a parse error")
+	}
+	if d := NewDateNormalizer(newTestContext(map[string]interface{}{"dates": `{"zone": "Mars/Olympus", "fields": []}`})); d.err == nil {
+		t.Error("expected an error for an unknown zone")
+	}
+}
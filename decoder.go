@@ -0,0 +1,190 @@
+package ghostdoc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/npolar/ghostdoc/context"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	autoEncoding   = "auto"
+	detectPeekSize = 1024
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// Decoder converts raw input in the configured encoding to UTF-8
+type Decoder struct {
+	context  context.GhostContext
+	name     string
+	encoding encoding.Encoding
+}
+
+// decodedReader reads the decoded stream and closes the underlying input
+type decodedReader struct {
+	io.Reader
+	io.Closer
+}
+
+// NewDecoder factory
+func NewDecoder(c context.GhostContext) (*Decoder, error) {
+	var err error
+	decoder := &Decoder{
+		context: c,
+		name:    strings.ToLower(c.GlobalString("encoding")),
+	}
+
+	if decoder.name != "" && decoder.name != autoEncoding {
+		if decoder.encoding, err = decoder.lookup(decoder.name); err != nil {
+			err = errors.New("[Encoding Error] " + err.Error())
+		}
+	}
+
+	return decoder, err
+}
+
+// decode wraps the input in a reader converting it to UTF-8. Input is
+// returned as is when no encoding is configured
+func (d *Decoder) decode(input io.ReadCloser) io.ReadCloser {
+	if d.name == "" {
+		return input
+	}
+
+	var reader io.Reader = input
+	enc := d.encoding
+	if d.name == autoEncoding {
+		buffered := bufio.NewReader(input)
+		peek, _ := buffered.Peek(detectPeekSize)
+		reader, enc = buffered, d.detect(peek)
+	}
+
+	return &decodedReader{
+		Reader: transform.NewReader(reader, enc.NewDecoder()),
+		Closer: input,
+	}
+}
+
+// decodeBytes converts a complete input to UTF-8
+func (d *Decoder) decodeBytes(data []byte) ([]byte, error) {
+	decoded, err := ioutil.ReadAll(d.decode(ioutil.NopCloser(bytes.NewReader(data))))
+	if err != nil {
+		err = errors.New("[Encoding Error] " + err.Error())
+	}
+	return decoded, err
+}
+
+// lookup resolves the encoding name. Common aliases are handled before
+// falling back on the IANA registry
+func (d *Decoder) lookup(name string) (encoding.Encoding, error) {
+	switch name {
+	case "utf-8", "utf8":
+		return unicode.UTF8BOM, nil
+	case "utf-16", "utf16":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), nil
+	case "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	case "latin1", "latin-1", "iso-8859-1":
+		return charmap.ISO8859_1, nil
+	case "windows-1252", "cp1252":
+		return charmap.Windows1252, nil
+	}
+
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err == nil && enc == nil {
+		err = errors.New("Unsupported encoding: " + name)
+	}
+	return enc, err
+}
+
+// detect guesses the encoding from byte order marks and byte patterns in the
+// start of the input. Anything that is not UTF-16 is read as UTF-8 with a
+// Windows-1252 (superset of the printable Latin-1 range) fallback
+func (d *Decoder) detect(peek []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(peek, utf8BOM):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(peek, utf16LEBOM):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(peek, utf16BEBOM):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	}
+
+	if even, odd := d.nullBytes(peek); even+odd > 0 {
+		if odd > even {
+			return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+		}
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+
+	return fallbackEncoding{}
+}
+
+// nullBytes counts the null bytes at even and odd positions. Text in UTF-16
+// has a null byte in every ascii char
+func (d *Decoder) nullBytes(data []byte) (int, int) {
+	var even, odd int
+	for i, b := range data {
+		if b == 0 {
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		}
+	}
+	return even, odd
+}
+
+// fallbackEncoding decodes valid UTF-8 as is and every byte that is not part
+// of a valid UTF-8 sequence as Windows-1252. This handles Latin-1 anywhere in
+// a stream, not only in the part used for detection
+type fallbackEncoding struct{}
+
+func (fallbackEncoding) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: fallbackTransformer{}}
+}
+
+func (fallbackEncoding) NewEncoder() *encoding.Encoder {
+	return unicode.UTF8.NewEncoder()
+}
+
+type fallbackTransformer struct {
+	transform.NopResetter
+}
+
+func (fallbackTransformer) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	var nDst, nSrc int
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			r = charmap.Windows1252.DecodeByte(src[nSrc])
+		}
+
+		if nDst+utf8.RuneLen(r) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += utf8.EncodeRune(dst[nDst:], r)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
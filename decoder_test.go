@@ -0,0 +1,38 @@
+package ghostdoc
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		encoding string
+		in       string
+		want     string
+	}{
+		{"", "Ny-Ålesund", "Ny-Ålesund"},
+		{"latin1", "Ny-\xc5lesund", "Ny-Ålesund"},
+		{"cp1252", "\x80 5", "€ 5"},
+		{"utf-16le", "A\x00\xe5\x00", "Aå"},
+		{"auto", "\xff\xfeA\x00\xe5\x00", "Aå"},
+		{"auto", "Å \xc5 \x80", "Å Å €"},
+		{"AUTO", strings.Repeat("ø\xf8", 5000), strings.Repeat("øø", 5000)},
+	}
+
+	for _, test := range tests {
+		d, err := NewDecoder(newTestContext(map[string]interface{}{"encoding": test.encoding}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := ioutil.ReadAll(d.decode(ioutil.NopCloser(strings.NewReader(test.in))))
+		if string(got) != test.want {
+			t.Errorf("%s: got %.40q, want %.40q", test.encoding, got, test.want)
+		}
+	}
+
+	if _, err := NewDecoder(newTestContext(map[string]interface{}{"encoding": "klingon"})); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+}
@@ -0,0 +1,56 @@
+package ghostdoc
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/npolar/ghostdoc/context"
+)
+
+// ExportCommand cli.Command for exporting documents from a paginated REST api
+func ExportCommand() cli.Command {
+	return cli.Command{
+		Name:  "export",
+		Usage: "export documents from a paginated JSON REST api",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "pagination, pg",
+				Value: "link",
+				Usage: "pagination mode [link|offset|cursor]",
+			},
+			cli.StringFlag{
+				Name:  "items, it",
+				Usage: "key holding the document list in the response. If not set the response should be an array",
+			},
+			cli.IntFlag{
+				Name:  "limit, l",
+				Value: 100,
+				Usage: "page size used in offset and cursor mode",
+			},
+			cli.StringFlag{
+				Name:  "limit-param",
+				Value: "limit",
+				Usage: "query parameter used for the page size",
+			},
+			cli.StringFlag{
+				Name:  "offset-param",
+				Value: "offset",
+				Usage: "query parameter used for the offset in offset mode",
+			},
+			cli.StringFlag{
+				Name:  "cursor-param",
+				Value: "cursor",
+				Usage: "query parameter used for the cursor in cursor mode",
+			},
+			cli.StringFlag{
+				Name:  "cursor-key",
+				Value: "next",
+				Usage: "key holding the next cursor (or next page url) in the response",
+			},
+		},
+		Action: processExport,
+	}
+}
+
+func processExport(c *cli.Context) {
+	exporter := NewExporter(context.NewCliContext(c))
+	exporter.process()
+}
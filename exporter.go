@@ -0,0 +1,260 @@
+package ghostdoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+const (
+	linkNextRegex = `<([^>]+)>\s*;[^,]*rel="?next"?`
+)
+
+// Exporter pages through a JSON REST api and pushes every document to the Writer
+type Exporter struct {
+	context  context.GhostContext
+	client   *http.Client
+	dataChan chan *dataFile
+}
+
+// NewExporter factory
+func NewExporter(c context.GhostContext) *Exporter {
+	util.ConfigureLogger(c)
+	return &Exporter{
+		context:  c,
+		client:   urlClient,
+		dataChan: make(chan *dataFile, c.GlobalInt("concurrency")),
+	}
+}
+
+func (e *Exporter) process() {
+	var start = time.Now()
+	writer := NewWriter(e.context, e.dataChan)
+	writerWaitGroup, err := writer.listen()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	go func() {
+		if len(e.context.Args()) == 0 {
+			log.Error("[Argument Error] Called without api url: " + e.context.Cli().App.Name + " export -h for usage info.")
+		}
+		for _, argument := range e.context.Args() {
+			if err := e.export(argument); err != nil {
+				log.WithFields(log.Fields{"input": argument}).Error(err.Error())
+			}
+		}
+		close(e.dataChan)
+	}()
+	writerWaitGroup.Wait()
+	writer.summary.log()
+
+	util.SendErrorMail()
+	log.Info("Stop, took: ", time.Now().Sub(start))
+}
+
+// export walks all pages of the api starting at address. The documents of
+// all pages are named after the address so they form one input
+func (e *Exporter) export(address string) error {
+	page, err := e.firstPage(address)
+	offset := 0
+	previous := ""
+
+	for err == nil && page != nil {
+		var docs []map[string]interface{}
+		var next *url.URL
+		log.Info("Exporting ", page.String())
+
+		if docs, next, err = e.fetchPage(page); err == nil {
+			// Servers that ignore the offset return the same page forever
+			if e.context.String("pagination") == "offset" && len(docs) > 0 {
+				content, _ := json.Marshal(docs)
+				if string(content) == previous {
+					log.WithFields(log.Fields{"input": address}).Warn("[Export Error] Page repeats the previous page, stopping at ", page.String())
+					break
+				}
+				previous = string(content)
+			}
+
+			for _, doc := range docs {
+				e.dataChan <- &dataFile{
+					name: address,
+					data: doc,
+				}
+			}
+
+			switch e.context.String("pagination") {
+			case "offset":
+				offset += len(docs)
+				page, err = e.offsetPage(page, offset, len(docs) == 0)
+			default:
+				if next != nil && next.String() == page.String() {
+					next = nil
+				}
+				page = next
+			}
+		}
+	}
+
+	if err != nil {
+		err = errors.New("[Export Error] " + err.Error())
+	}
+	return err
+}
+
+// firstPage parses the address and adds the paging parameters used by the mode
+func (e *Exporter) firstPage(address string) (*url.URL, error) {
+	page, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.context.String("pagination") {
+	case "link":
+	case "offset":
+		return e.offsetPage(page, 0, false)
+	case "cursor":
+		query := page.Query()
+		query.Set(e.context.String("limit-param"), strconv.Itoa(e.context.Int("limit")))
+		page.RawQuery = query.Encode()
+	default:
+		err = errors.New("Unsupported pagination mode: " + e.context.String("pagination"))
+	}
+
+	return page, err
+}
+
+// offsetPage returns the page starting at offset, or nil when the previous
+// page was empty. Servers may cap the page size below the limit, so a short
+// page does not mean the end
+func (e *Exporter) offsetPage(page *url.URL, offset int, empty bool) (*url.URL, error) {
+	if empty {
+		return nil, nil
+	}
+	limit := e.context.Int("limit")
+
+	next := *page
+	query := next.Query()
+	query.Set(e.context.String("limit-param"), strconv.Itoa(limit))
+	query.Set(e.context.String("offset-param"), strconv.Itoa(offset))
+	next.RawQuery = query.Encode()
+	return &next, nil
+}
+
+// fetchPage requests a single page and returns its documents and the next page if any
+func (e *Exporter) fetchPage(page *url.URL) ([]map[string]interface{}, *url.URL, error) {
+	req, err := http.NewRequest("GET", page.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("%s responded with %s", page.String(), resp.Status)
+	}
+
+	var body interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, nil, err
+	}
+
+	docs, err := e.documents(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *url.URL
+	switch e.context.String("pagination") {
+	case "link":
+		next, err = e.linkNext(page, resp.Header.Get("Link"))
+	case "cursor":
+		next, err = e.cursorNext(page, body)
+	}
+
+	return docs, next, err
+}
+
+// documents extracts the document list from the response body
+func (e *Exporter) documents(body interface{}) ([]map[string]interface{}, error) {
+	if key := e.context.String("items"); key != "" {
+		if object, ok := body.(map[string]interface{}); ok {
+			body = object[key]
+		} else {
+			return nil, errors.New("Response is not an object, could not find items key: " + key)
+		}
+	}
+
+	var docs []map[string]interface{}
+	switch items := body.(type) {
+	case []interface{}:
+		for _, item := range items {
+			if doc, ok := item.(map[string]interface{}); ok {
+				docs = append(docs, doc)
+			} else {
+				log.Warn("[Export Error] Skipping non object item: ", item)
+			}
+		}
+	case map[string]interface{}:
+		docs = append(docs, items)
+	case nil:
+	default:
+		return nil, fmt.Errorf("Unexpected response items: %v", items)
+	}
+
+	return docs, nil
+}
+
+// linkNext resolves the rel="next" url of a Link header
+func (e *Exporter) linkNext(page *url.URL, header string) (*url.URL, error) {
+	matches := regexp.MustCompile(linkNextRegex).FindStringSubmatch(header)
+	if matches == nil {
+		return nil, nil
+	}
+	return page.Parse(matches[1])
+}
+
+// cursorNext reads the cursor from the response body. A cursor containing a
+// url is followed as is, anything else is set as the cursor query parameter
+func (e *Exporter) cursorNext(page *url.URL, body interface{}) (*url.URL, error) {
+	object, ok := body.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var cursor string
+	switch value := object[e.context.String("cursor-key")].(type) {
+	case string:
+		cursor = value
+	case float64:
+		cursor = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	if cursor == "" {
+		return nil, nil
+	}
+
+	if next, err := url.Parse(cursor); err == nil && next.IsAbs() {
+		return next, nil
+	}
+
+	next := *page
+	query := next.Query()
+	query.Set(e.context.String("cursor-param"), cursor)
+	next.RawQuery = query.Encode()
+	return &next, nil
+}
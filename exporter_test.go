@@ -0,0 +1,140 @@
+package ghostdoc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// exportTestServer serves ids 1 to 5. Link and cursor pages hold two ids,
+// offset pages are capped at two ids whatever the requested limit
+func exportTestServer() *httptest.Server {
+	ids := []int{1, 2, 3, 4, 5}
+	page := func(start int) []map[string]interface{} {
+		docs := []map[string]interface{}{}
+		for i := start; i < start+2 && i < len(ids); i++ {
+			docs = append(docs, map[string]interface{}{"id": ids[i]})
+		}
+		return docs
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		if start+2 < len(ids) {
+			w.Header().Set("Link", `</link?start=`+strconv.Itoa(start+2)+`>; rel="next"`)
+		}
+		json.NewEncoder(w).Encode(page(start))
+	})
+	mux.HandleFunc("/offset", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"entries": page(offset)})
+	})
+	mux.HandleFunc("/repeat", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(page(0))
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		body := map[string]interface{}{"entries": page(start)}
+		if start+2 < len(ids) {
+			body["next"] = strconv.Itoa(start + 2)
+		}
+		json.NewEncoder(w).Encode(body)
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestExport(t *testing.T) {
+	server := exportTestServer()
+	defer server.Close()
+
+	all := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		path   string
+		want   []float64
+		err    bool
+	}{
+		{
+			name:   "link",
+			values: map[string]interface{}{"pagination": "link"},
+			path:   "/link",
+			want:   all,
+		},
+		{
+			name:   "offset continues after short pages",
+			values: map[string]interface{}{"pagination": "offset", "items": "entries", "limit": 3},
+			path:   "/offset",
+			want:   all,
+		},
+		{
+			name:   "offset stops on a repeated page",
+			values: map[string]interface{}{"pagination": "offset"},
+			path:   "/repeat",
+			want:   []float64{1, 2},
+		},
+		{
+			name:   "cursor",
+			values: map[string]interface{}{"pagination": "cursor", "items": "entries"},
+			path:   "/cursor",
+			want:   all,
+		},
+		{
+			name:   "unsupported mode",
+			values: map[string]interface{}{"pagination": "pages"},
+			path:   "/link",
+			err:    true,
+		},
+		{
+			name:   "error response",
+			values: map[string]interface{}{"pagination": "link"},
+			path:   "/fail",
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values := map[string]interface{}{
+				"limit":        2,
+				"limit-param":  "limit",
+				"offset-param": "offset",
+				"cursor-param": "cursor",
+				"cursor-key":   "next",
+			}
+			for key, value := range test.values {
+				values[key] = value
+			}
+
+			e := NewExporter(newTestContext(values))
+			done := make(chan error, 1)
+			go func() {
+				done <- e.export(server.URL + test.path)
+				close(e.dataChan)
+			}()
+
+			var got []float64
+			for data := range e.dataChan {
+				if data.name != server.URL+test.path {
+					t.Errorf("document named %q, want the address", data.name)
+				}
+				got = append(got, data.data["id"].(float64))
+			}
+			err := <-done
+
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got ids %v, want %v", got, test.want)
+			}
+		})
+	}
+}
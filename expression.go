@@ -0,0 +1,515 @@
+package ghostdoc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/npolar/ghostdoc/util"
+)
+
+// expression is a compiled expression evaluated against a document
+type expression func(data map[string]interface{}) (interface{}, error)
+
+// expressionParser is a recursive descent parser for a small, side effect
+// free expression language:
+//
+//	MsgType == 30 && IridCEP < 5
+//	exists(temperature) && !(station.name =~ "^test")
+//
+// Identifiers are key paths (a.b.0), use backticks for keys with other chars.
+// Operators: || && ! == != < <= > >= =~ + - * / % and parentheses.
+// Functions: exists(key), len(x), lower(x), upper(x), contains(x, y), number(x)
+type expressionParser struct {
+	src    string
+	tokens []string
+	pos    int
+}
+
+const (
+	exprTokenRegex = "\\s*(\\d+(?:\\.\\d+)?(?:[eE][-+]?\\d+)?|\"(?:[^\"\\\\]|\\\\.)*\"|'(?:[^'\\\\]|\\\\.)*'|`[^`]*`|[A-Za-z_][A-Za-z0-9_.]*|==|!=|<=|>=|=~|&&|\\|\\||[-+*/%<>!(),])"
+)
+
+// compileExpression parses the source into an expression
+func compileExpression(src string) (expression, error) {
+	p := &expressionParser{src: src}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+
+	expr, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", src, err)
+	}
+	return expr, nil
+}
+
+func (p *expressionParser) tokenize() error {
+	tokenRegex := regexp.MustCompile(exprTokenRegex)
+	rest := p.src
+	for strings.TrimSpace(rest) != "" {
+		loc := tokenRegex.FindStringSubmatchIndex(rest)
+		if loc == nil || loc[0] != 0 {
+			return fmt.Errorf("invalid expression %q: unexpected input at %q", p.src, strings.TrimSpace(rest))
+		}
+		p.tokens = append(p.tokens, rest[loc[2]:loc[3]])
+		rest = rest[loc[1]:]
+	}
+	return nil
+}
+
+func (p *expressionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *expressionParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *expressionParser) expect(token string) error {
+	if next := p.next(); next != token {
+		return fmt.Errorf("expected %q, got %q", token, next)
+	}
+	return nil
+}
+
+func (p *expressionParser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek() == "||" {
+		p.next()
+		var right expression
+		if right, err = p.parseAnd(); err == nil {
+			left = p.logical(left, right, true)
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) parseAnd() (expression, error) {
+	left, err := p.parseNot()
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		var right expression
+		if right, err = p.parseNot(); err == nil {
+			left = p.logical(left, right, false)
+		}
+	}
+	return left, err
+}
+
+// logical short circuits: || returns true on the first truthy operand, && false on the first falsy
+func (p *expressionParser) logical(left, right expression, or bool) expression {
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil || truthy(l) == or {
+			return truthy(l), err
+		}
+		r, err := right(data)
+		return truthy(r), err
+	}
+}
+
+func (p *expressionParser) parseNot() (expression, error) {
+	if p.peek() != "!" {
+		return p.parseComparison()
+	}
+
+	p.next()
+	operand, err := p.parseNot()
+	return func(data map[string]interface{}) (interface{}, error) {
+		v, err := operand(data)
+		return !truthy(v), err
+	}, err
+}
+
+func (p *expressionParser) parseComparison() (expression, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	operator := p.peek()
+	switch operator {
+	case "==", "!=", "<", "<=", ">", ">=", "=~":
+		p.next()
+	default:
+		return left, nil
+	}
+
+	start := p.pos
+	right, err := p.parseSum()
+	if err == nil && operator == "=~" && p.pos == start+1 && isExpressionString(p.tokens[start]) {
+		return p.match(left, p.tokens[start])
+	}
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(data)
+		if err != nil {
+			return nil, err
+		}
+		return compareValues(operator, l, r)
+	}, err
+}
+
+// match compiles a constant =~ pattern once, other patterns are compiled when evaluated
+func (p *expressionParser) match(left expression, token string) (expression, error) {
+	re, err := regexp.Compile(unquoteExpressionString(token))
+	if err != nil {
+		return nil, err
+	}
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil {
+			return nil, err
+		}
+		return l != nil && re.MatchString(toText(l)), nil
+	}, nil
+}
+
+func (p *expressionParser) parseSum() (expression, error) {
+	left, err := p.parseProduct()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		operator := p.next()
+		var right expression
+		if right, err = p.parseProduct(); err == nil {
+			left = p.arithmetic(operator, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) parseProduct() (expression, error) {
+	left, err := p.parseUnary()
+	for err == nil && (p.peek() == "*" || p.peek() == "/" || p.peek() == "%") {
+		operator := p.next()
+		var right expression
+		if right, err = p.parseUnary(); err == nil {
+			left = p.arithmetic(operator, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) arithmetic(operator string, left, right expression) expression {
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if operator == "+" {
+			if ls, ok := l.(string); ok {
+				return ls + fmt.Sprint(r), nil
+			}
+		}
+
+		ln, lok := toNumber(l)
+		rn, rok := toNumber(r)
+		if !lok || !rok {
+			return nil, nil
+		}
+
+		switch operator {
+		case "+":
+			return ln + rn, nil
+		case "-":
+			return ln - rn, nil
+		case "*":
+			return ln * rn, nil
+		case "/":
+			return ln / rn, nil
+		default:
+			return math.Mod(ln, rn), nil
+		}
+	}
+}
+
+func (p *expressionParser) parseUnary() (expression, error) {
+	if p.peek() != "-" {
+		return p.parsePrimary()
+	}
+
+	p.next()
+	operand, err := p.parseUnary()
+	return func(data map[string]interface{}) (interface{}, error) {
+		v, err := operand(data)
+		if n, ok := toNumber(v); ok {
+			return -n, err
+		}
+		return nil, err
+	}, err
+}
+
+func (p *expressionParser) parsePrimary() (expression, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		expr, err := p.parseOr()
+		if err == nil {
+			err = p.expect(")")
+		}
+		return expr, err
+	case token[0] >= '0' && token[0] <= '9':
+		number, err := strconv.ParseFloat(token, 64)
+		return constant(number), err
+	case isExpressionString(token):
+		return constant(unquoteExpressionString(token)), nil
+	case token[0] == '`':
+		return keyPath(token[1 : len(token)-1]), nil
+	case token == "true" || token == "false":
+		return constant(token == "true"), nil
+	case token == "null":
+		return constant(nil), nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		if p.peek() == "(" {
+			return p.parseCall(token)
+		}
+		return keyPath(token), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+func (p *expressionParser) parseCall(name string) (expression, error) {
+	p.next()
+
+	// exists takes the key itself, not its value
+	if name == "exists" {
+		path := strings.Trim(p.next(), "`\"'")
+		return func(data map[string]interface{}) (interface{}, error) {
+			v, ok := util.GetPath(data, path)
+			return ok && v != nil, nil
+		}, p.expect(")")
+	}
+
+	fn, ok := expressionFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+
+	var args []expression
+	for p.peek() != ")" {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+
+	return func(data map[string]interface{}) (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			var err error
+			if values[i], err = arg(data); err != nil {
+				return nil, err
+			}
+		}
+		return fn(values)
+	}, nil
+}
+
+var expressionFunctions = map[string]func(args []interface{}) (interface{}, error){
+	"len": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("len takes one argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len([]rune(v))), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return float64(0), nil
+	},
+	"lower": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("lower takes one argument")
+		}
+		return strings.ToLower(toText(args[0])), nil
+	},
+	"upper": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("upper takes one argument")
+		}
+		return strings.ToUpper(toText(args[0])), nil
+	},
+	"contains": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("contains takes two arguments")
+		}
+		if list, ok := args[0].([]interface{}); ok {
+			for _, item := range list {
+				if equalValues(item, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(toText(args[0]), toText(args[1])), nil
+	},
+	"number": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("number takes one argument")
+		}
+		if n, ok := toNumber(args[0]); ok {
+			return n, nil
+		}
+		return nil, nil
+	},
+}
+
+func constant(value interface{}) expression {
+	return func(data map[string]interface{}) (interface{}, error) {
+		return value, nil
+	}
+}
+
+func keyPath(path string) expression {
+	return func(data map[string]interface{}) (interface{}, error) {
+		v, _ := util.GetPath(data, path)
+		return v, nil
+	}
+}
+
+func isExpressionString(token string) bool {
+	return token != "" && (token[0] == '"' || token[0] == '\'')
+}
+
+func unquoteExpressionString(token string) string {
+	if token[0] == '\'' {
+		token = "\"" + strings.Replace(token[1:len(token)-1], "\"", "\\\"", -1) + "\""
+	}
+	if s, err := strconv.Unquote(token); err == nil {
+		return s
+	}
+	return token[1 : len(token)-1]
+}
+
+// compareValues compares numerically when both sides are numbers (or numeric
+// strings) and as strings otherwise. Comparisons involving null are false,
+// except for equality
+func compareValues(operator string, l, r interface{}) (interface{}, error) {
+	switch operator {
+	case "==":
+		return equalValues(l, r), nil
+	case "!=":
+		return !equalValues(l, r), nil
+	case "=~":
+		re, err := regexp.Compile(toText(r))
+		if err != nil {
+			return nil, err
+		}
+		return l != nil && re.MatchString(toText(l)), nil
+	}
+
+	if l == nil || r == nil {
+		return false, nil
+	}
+
+	var cmp int
+	ln, lok := toNumber(l)
+	rn, rok := toNumber(r)
+	if lok && rok {
+		switch {
+		case ln < rn:
+			cmp = -1
+		case ln > rn:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(toText(l), toText(r))
+	}
+
+	switch operator {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func equalValues(l, r interface{}) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if ln, ok := toNumber(l); ok {
+		if rn, ok := toNumber(r); ok {
+			return ln == rn
+		}
+	}
+	if lb, ok := l.(bool); ok {
+		rb, ok := r.(bool)
+		return ok && lb == rb
+	}
+	return toText(l) == toText(r)
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func toText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
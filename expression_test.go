@@ -0,0 +1,90 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileExpression(t *testing.T) {
+	data := map[string]interface{}{
+		"MsgType":  30.0,
+		"IridCEP":  "3",
+		"station":  map[string]interface{}{"name": "Test station", "ids": []interface{}{"a", "b"}},
+		"key-dash": "x",
+		"empty":    nil,
+	}
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{`MsgType == 30 && IridCEP < 5`, true},
+		{`MsgType == 30 && IridCEP > 5`, false},
+		{`MsgType != 30 || IridCEP == "3"`, true},
+		{`!(MsgType == 31)`, true},
+		{`MsgType * 2 + 1`, 61.0},
+		{`-MsgType % 7`, -2.0},
+		{`"a" + "b"`, "ab"},
+		{`station.name =~ "^Test"`, true},
+		{`station.name =~ 'station$'`, true},
+		{`station.name =~ "^test"`, false},
+		{`station.name =~ "^" + "Test"`, true},
+		{`empty =~ ".*"`, false},
+		{"`key-dash` == 'x'", true},
+		{`exists(station.name) && !exists(nope) && !exists(empty)`, true},
+		{`len(station.ids) == 2 && len(station.name) == 12`, true},
+		{`lower(station.name) == "test station" && upper("a") == "A"`, true},
+		{`contains(station.ids, "b") && contains(station.name, "stat")`, true},
+		{`number(IridCEP) + 1`, 4.0},
+		{`nope < 1`, false},
+		{`nope == null`, true},
+		{`"10" > "9"`, true},
+		{`"b" > "a"`, true},
+	}
+
+	for _, test := range tests {
+		expr, err := compileExpression(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		got, err := expr(data)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s = %v, want %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{`a ==`, "unexpected end"},
+		{`a == 1)`, `unexpected ")"`},
+		{`nope(a)`, "unknown function"},
+		{`a # 1`, "unexpected input"},
+		{`a =~ "(unclosed"`, "missing closing )"},
+	}
+
+	for _, test := range tests {
+		if _, err := compileExpression(test.src); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.src, err, test.err)
+		}
+	}
+}
+
+func TestDynamicPatternErrors(t *testing.T) {
+	expr, err := compileExpression(`name =~ pattern`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expr(map[string]interface{}{"name": "a", "pattern": "("}); err == nil {
+		t.Error("expected an error for an invalid pattern from the document")
+	}
+}
@@ -0,0 +1,41 @@
+package ghostdoc
+
+import (
+	"errors"
+
+	"github.com/npolar/ghostdoc/context"
+)
+
+// Filter drops documents that do not match the --where expression
+type Filter struct {
+	context context.GhostContext
+	where   expression
+	err     error
+}
+
+// NewFilter factory
+func NewFilter(c context.GhostContext) *Filter {
+	f := &Filter{context: c}
+	if where := c.GlobalString("where"); where != "" {
+		f.where, f.err = compileExpression(where)
+	}
+	return f
+}
+
+// filter returns nil for documents that should be dropped
+func (f *Filter) filter(data map[string]interface{}) (map[string]interface{}, error) {
+	if f.err != nil {
+		return data, errors.New("where: " + f.err.Error())
+	}
+
+	if f.where != nil {
+		result, err := f.where(data)
+		if err != nil {
+			return data, errors.New("where: " + err.Error())
+		}
+		if !truthy(result) {
+			return nil, nil
+		}
+	}
+	return data, nil
+}
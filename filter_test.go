@@ -0,0 +1,30 @@
+package ghostdoc
+
+import (
+	"testing"
+)
+
+func TestWhereDropsAndCounts(t *testing.T) {
+	dataChan := make(chan *dataFile)
+	w := NewWriter(newTestContext(map[string]interface{}{"concurrency": 2, "where": "MsgType == 30 && IridCEP < 5"}), dataChan)
+	wg, err := w.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cep := range []float64{3, 4, 8} {
+		dataChan <- &dataFile{name: "test", data: map[string]interface{}{"MsgType": 30.0, "IridCEP": cep}}
+	}
+	close(dataChan)
+	wg.Wait()
+
+	if w.summary.documents != 3 || w.summary.published != 2 || w.summary.dropped != 1 {
+		t.Errorf("got %+v, want 3 documents, 2 published and 1 dropped", w.summary)
+	}
+}
+
+func TestFilterCompileError(t *testing.T) {
+	f := NewFilter(newTestContext(map[string]interface{}{"where": "MsgType == "}))
+	if _, err := f.filter(map[string]interface{}{}); f.err == nil || err == nil {
+		t.Error("expected the compile error")
+	}
+}
@@ -0,0 +1,250 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+var (
+	coordinateNumberRegex = regexp.MustCompile(`[-+]?\d+(?:[.,]\d+)?`)
+	coordinateHemiRegex   = regexp.MustCompile(`(?i)^\s*([NSEW])|([NSEW])\s*$`)
+)
+
+// geometrySpec is the --geometry configuration
+type geometrySpec struct {
+	Type    string `json:"type"`
+	Lat     string `json:"lat"`
+	Lon     string `json:"lon"`
+	Alt     string `json:"alt"`
+	Target  string `json:"target"`
+	Remove  bool   `json:"remove"`
+	OnError string `json:"on_error"`
+}
+
+// GeometryBuilder builds a GeoJSON geometry from latitude and longitude
+// fields. Coordinates can be decimal degrees, degrees minutes seconds and
+// carry a hemisphere letter (78°13'23.5"N, 15 38.2 E). Scalars give a Point,
+// arrays a LineString or Polygon.
+type GeometryBuilder struct {
+	context context.GhostContext
+	spec    geometrySpec
+	err     error
+}
+
+// NewGeometryBuilder factory
+func NewGeometryBuilder(c context.GhostContext) *GeometryBuilder {
+	g := &GeometryBuilder{context: c}
+	if spec := c.GlobalString("geometry"); spec != "" {
+		if g.err = util.ReadConfig(spec, &g.spec); g.err == nil {
+			g.err = g.compile()
+		}
+	}
+	return g
+}
+
+func (g *GeometryBuilder) compile() error {
+	if g.spec.Lat == "" || g.spec.Lon == "" {
+		return errors.New("geometry needs both lat and lon")
+	}
+	if g.spec.Target == "" {
+		g.spec.Target = "geometry"
+	}
+
+	switch g.spec.Type {
+	case "", "Point", "LineString", "Polygon":
+	default:
+		return fmt.Errorf("unsupported geometry type %q", g.spec.Type)
+	}
+	return nil
+}
+
+func (g *GeometryBuilder) build(data map[string]interface{}) (map[string]interface{}, error) {
+	if g.err != nil {
+		return data, errors.New("geometry: " + g.err.Error())
+	}
+
+	lat, latOk := util.GetPath(data, g.spec.Lat)
+	lon, lonOk := util.GetPath(data, g.spec.Lon)
+	if !latOk || !lonOk || lat == nil || lon == nil || lat == "" || lon == "" {
+		return data, nil
+	}
+
+	var alt interface{}
+	if g.spec.Alt != "" {
+		alt, _ = util.GetPath(data, g.spec.Alt)
+	}
+
+	geometry, err := g.geometry(lat, lon, alt)
+	if err != nil {
+		err = fmt.Errorf("geometry: %v", err)
+		if g.spec.OnError == "warn" {
+			log.Warn(err.Error())
+			return data, nil
+		}
+		return data, err
+	}
+
+	if g.spec.Remove {
+		var keys []string
+		for _, key := range []string{g.spec.Lat, g.spec.Lon, g.spec.Alt} {
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+		util.DeletePaths(data, keys)
+	}
+
+	if err = util.SetPath(data, g.spec.Target, geometry); err != nil {
+		return data, errors.New("geometry: " + err.Error())
+	}
+	return data, nil
+}
+
+// geometry returns a Point for scalar coordinates and a LineString or Polygon for arrays
+func (g *GeometryBuilder) geometry(lat, lon, alt interface{}) (map[string]interface{}, error) {
+	lats, latArray := lat.([]interface{})
+	lons, lonArray := lon.([]interface{})
+	if latArray != lonArray {
+		return nil, errors.New("lat and lon must both be values or both be arrays")
+	}
+
+	if !latArray {
+		if g.spec.Type != "" && g.spec.Type != "Point" {
+			return nil, fmt.Errorf("%s needs coordinate arrays", g.spec.Type)
+		}
+		position, err := g.position(lat, lon, alt)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "Point", "coordinates": position}, nil
+	}
+
+	if len(lats) != len(lons) {
+		return nil, fmt.Errorf("lat has %d and lon %d values", len(lats), len(lons))
+	}
+	alts, _ := alt.([]interface{})
+	if alt != nil && len(alts) != len(lats) {
+		alts = nil
+	}
+
+	var line []interface{}
+	for i := range lats {
+		var altValue interface{}
+		if alts != nil {
+			altValue = alts[i]
+		}
+		position, err := g.position(lats[i], lons[i], altValue)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %v", i, err)
+		}
+		line = append(line, position)
+	}
+
+	switch g.spec.Type {
+	case "Polygon":
+		return g.polygon(line)
+	case "Point":
+		return nil, errors.New("Point needs single coordinate values")
+	}
+	if len(line) < 2 {
+		return nil, errors.New("LineString needs at least two positions")
+	}
+	return map[string]interface{}{"type": "LineString", "coordinates": line}, nil
+}
+
+// polygon closes the ring when the last position differs from the first
+func (g *GeometryBuilder) polygon(ring []interface{}) (map[string]interface{}, error) {
+	if len(ring) > 0 && !equalValues(ring[0], ring[len(ring)-1]) {
+		ring = append(ring, ring[0])
+	}
+	if len(ring) < 4 {
+		return nil, errors.New("Polygon needs at least three distinct positions")
+	}
+	return map[string]interface{}{"type": "Polygon", "coordinates": []interface{}{ring}}, nil
+}
+
+// position returns a GeoJSON position [lon, lat(, alt)]
+func (g *GeometryBuilder) position(lat, lon, alt interface{}) ([]interface{}, error) {
+	latValue, err := parseCoordinate(lat, "NS", 90)
+	if err != nil {
+		return nil, fmt.Errorf("lat: %v", err)
+	}
+	lonValue, err := parseCoordinate(lon, "EW", 180)
+	if err != nil {
+		return nil, fmt.Errorf("lon: %v", err)
+	}
+
+	position := []interface{}{lonValue, latValue}
+	if alt != nil && alt != "" {
+		altValue, ok := toNumber(alt)
+		if !ok {
+			return nil, fmt.Errorf("alt: %v is not a number", alt)
+		}
+		position = append(position, altValue)
+	}
+	return position, nil
+}
+
+// parseCoordinate converts decimal degrees or degrees minutes seconds with
+// an optional hemisphere letter to signed decimal degrees within limit
+func parseCoordinate(value interface{}, hemispheres string, limit float64) (float64, error) {
+	var degrees float64
+	if number, ok := value.(float64); ok {
+		degrees = number
+	} else {
+		text := strings.TrimSpace(toText(value))
+		parsed, err := parseDMS(text, hemispheres)
+		if err != nil {
+			return 0, fmt.Errorf("%q %v", text, err)
+		}
+		degrees = parsed
+	}
+
+	if math.IsNaN(degrees) || math.Abs(degrees) > limit {
+		return 0, fmt.Errorf("%v is out of range [-%v, %v]", degrees, limit, limit)
+	}
+	return degrees, nil
+}
+
+func parseDMS(text string, hemispheres string) (float64, error) {
+	sign := 1.0
+	if match := coordinateHemiRegex.FindStringSubmatch(text); match != nil {
+		hemisphere := strings.ToUpper(match[1] + match[2])
+		if !strings.Contains(hemispheres, hemisphere) {
+			return 0, fmt.Errorf("has hemisphere %s, expected one of %s", hemisphere, hemispheres)
+		}
+		if hemisphere == "S" || hemisphere == "W" {
+			sign = -1
+		}
+	}
+
+	parts := coordinateNumberRegex.FindAllString(text, -1)
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, errors.New("is not a coordinate")
+	}
+
+	var total float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.Replace(part, ",", ".", 1), 64)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			if number < 0 || strings.HasPrefix(part, "-") {
+				sign, number = -sign, math.Abs(number)
+			}
+		} else if number < 0 || number >= 60 || strings.ContainsAny(part, "+-") {
+			return 0, errors.New("has minutes or seconds out of range")
+		}
+		total += number / math.Pow(60, float64(i))
+	}
+	return sign * total, nil
+}
@@ -0,0 +1,55 @@
+package ghostdoc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGeometryBuild(t *testing.T) {
+	g := NewGeometryBuilder(newTestContext(map[string]interface{}{"geometry": `{"lat": "pos.lat", "lon": "pos.lon", "alt": "pos.alt", "remove": true}`}))
+	got, err := g.build(map[string]interface{}{"pos": map[string]interface{}{"lat": "78 30 N", "lon": "15 15 W", "alt": 12.0}})
+	want := map[string]interface{}{"pos": map[string]interface{}{}, "geometry": map[string]interface{}{"type": "Point", "coordinates": []interface{}{-15.25, 78.5, 12.0}}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("point: got %v, %v, want %v", got, err, want)
+	}
+	if _, err := g.build(map[string]interface{}{"pos": map[string]interface{}{"lat": 91.0, "lon": 0.0}}); err == nil {
+		t.Error("expected an error for a latitude out of range")
+	}
+
+	g = NewGeometryBuilder(newTestContext(map[string]interface{}{"geometry": `{"type": "Polygon", "lat": "lat", "lon": "lon", "remove": true}`}))
+	got, err = g.build(map[string]interface{}{"lat": []interface{}{0.0, 0.0, 1.0}, "lon": []interface{}{0.0, 1.0, 1.0}})
+	ring := []interface{}{[]interface{}{0.0, 0.0}, []interface{}{1.0, 0.0}, []interface{}{1.0, 1.0}, []interface{}{0.0, 0.0}}
+	want = map[string]interface{}{"geometry": map[string]interface{}{"type": "Polygon", "coordinates": []interface{}{ring}}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("polygon: got %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		in          interface{}
+		hemispheres string
+		want        float64
+		err         bool
+	}{
+		{"-12,5", "NS", -12.5, false},
+		{`78°13'23.5"N`, "NS", 78 + 13.0/60 + 23.5/3600, false},
+		{"S 12 30", "NS", -12.5, false},
+		{"15 38.2 W", "EW", -(15 + 38.2/60), false},
+		{"12 30 E", "NS", 0, true},
+		{"12 61", "NS", 0, true},
+		{181.0, "EW", 0, true},
+	}
+
+	for _, test := range tests {
+		limit := 90.0
+		if test.hemispheres == "EW" {
+			limit = 180
+		}
+		got, err := parseCoordinate(test.in, test.hemispheres, limit)
+		if (err != nil) != test.err || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%v: got %v, %v, want %v", test.in, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// groupAggregate configures an aggregate value of a group
+type groupAggregate struct {
+	Key    string `json:"key"`
+	Op     string `json:"op"`
+	Target string `json:"target"`
+}
+
+// groupSpec is the --group configuration
+type groupSpec struct {
+	Keys       []string          `json:"keys"`
+	Scope      string            `json:"scope"`
+	Rows       *string           `json:"rows"`
+	Aggregates []*groupAggregate `json:"aggregates"`
+}
+
+// group collects the rows sharing the same key values
+type group struct {
+	name   string
+	source *rawFile
+	values []interface{}
+	rows   []map[string]interface{}
+}
+
+// Grouper collects documents by key fields and emits one document per group
+// holding the rows and the configured aggregates. Groups are emitted at the
+// end of every input file, or at the end of the run when scope is "run".
+type Grouper struct {
+	context context.GhostContext
+	spec    *groupSpec
+	err     error
+}
+
+// NewGrouper factory
+func NewGrouper(c context.GhostContext) *Grouper {
+	g := &Grouper{context: c}
+	if spec := c.GlobalString("group"); spec != "" {
+		g.spec = &groupSpec{}
+		if g.err = util.ReadConfig(spec, g.spec); g.err == nil {
+			g.err = g.validate()
+		}
+		if g.err != nil {
+			g.err = errors.New("[Group Error] " + g.err.Error())
+		}
+	}
+	return g
+}
+
+func (g *Grouper) validate() error {
+	if len(g.spec.Keys) == 0 {
+		return errors.New("group needs at least one key")
+	}
+	if g.spec.Rows == nil {
+		rows := "rows"
+		g.spec.Rows = &rows
+	}
+
+	for _, aggregate := range g.spec.Aggregates {
+		switch aggregate.Op {
+		case "first", "last", "min", "max", "mean", "count":
+		default:
+			return fmt.Errorf("Unknown aggregate %q", aggregate.Op)
+		}
+		if aggregate.Key == "" && aggregate.Op != "count" {
+			return fmt.Errorf("Aggregate %s needs a key", aggregate.Op)
+		}
+		if aggregate.Target == "" {
+			aggregate.Target = strings.Trim(aggregate.Key+"_"+aggregate.Op, "_")
+		}
+	}
+	return nil
+}
+
+func (g *Grouper) stage(in chan *dataFile) chan *dataFile {
+	if g.spec == nil {
+		return in
+	}
+
+	out := make(chan *dataFile, cap(in))
+	go func() {
+		var order []string
+		var input interface{}
+		groups := make(map[string]*group)
+
+		for data := range in {
+			if g.spec.Scope != "run" && data.input() != input && len(order) > 0 {
+				g.flush(order, groups, out)
+				order, groups = nil, make(map[string]*group)
+			}
+			input = data.input()
+
+			key, values := keyValues(data.data, g.spec.Keys)
+			if _, ok := groups[key]; !ok {
+				groups[key] = &group{name: data.name, source: data.source, values: values}
+				order = append(order, key)
+			}
+			groups[key].rows = append(groups[key].rows, data.data)
+		}
+
+		g.flush(order, groups, out)
+		close(out)
+	}()
+	return out
+}
+
+// keyValues returns the values at the key paths and a string key identifying them
+func keyValues(data map[string]interface{}, keys []string) (string, []interface{}) {
+	values := make([]interface{}, len(keys))
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		values[i], _ = util.GetPath(data, key)
+		parts[i] = fmt.Sprintf("%T:%v", values[i], values[i])
+	}
+	return strings.Join(parts, lookupKeySep), values
+}
+
+// flush emits the groups in order of their first row
+func (g *Grouper) flush(order []string, groups map[string]*group, out chan *dataFile) {
+	for _, key := range order {
+		out <- &dataFile{
+			name:   groups[key].name,
+			data:   g.document(groups[key]),
+			source: groups[key].source,
+		}
+	}
+}
+
+func (g *Grouper) document(grp *group) map[string]interface{} {
+	doc := make(map[string]interface{})
+	for i, key := range g.spec.Keys {
+		util.SetPath(doc, key, grp.values[i])
+	}
+
+	if *g.spec.Rows != "" {
+		rows := make([]interface{}, len(grp.rows))
+		for i, row := range grp.rows {
+			rows[i] = row
+		}
+		doc[*g.spec.Rows] = rows
+	}
+
+	for _, aggregate := range g.spec.Aggregates {
+		util.SetPath(doc, aggregate.Target, g.aggregate(aggregate, grp.rows))
+	}
+	return doc
+}
+
+// aggregate computes the aggregate over the rows. Missing, null and empty values are
+// skipped, min and max compare numbers numerically and anything else as text
+func (g *Grouper) aggregate(aggregate *groupAggregate, rows []map[string]interface{}) interface{} {
+	var values []interface{}
+	for _, row := range rows {
+		if aggregate.Key == "" {
+			values = append(values, row)
+		} else if value, ok := util.GetPath(row, aggregate.Key); ok && value != nil && value != "" {
+			values = append(values, value)
+		}
+	}
+
+	if aggregate.Op == "count" {
+		return float64(len(values))
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	switch aggregate.Op {
+	case "first":
+		return values[0]
+	case "last":
+		return values[len(values)-1]
+	case "mean":
+		var sum float64
+		var count int
+		for _, value := range values {
+			if number, ok := toNumber(value); ok {
+				sum += number
+				count++
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		return sum / float64(count)
+	default:
+		result := values[0]
+		for _, value := range values[1:] {
+			less, _ := compareValues("<", value, result)
+			if less == (aggregate.Op == "min") {
+				result = value
+			}
+		}
+		return result
+	}
+}
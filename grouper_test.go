@@ -0,0 +1,42 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrouper(t *testing.T) {
+	troll1 := map[string]interface{}{"station": "troll", "temp": 2.0, "time": "t1"}
+	zeppelin := map[string]interface{}{"station": "zeppelin", "temp": -1.0, "time": "t1"}
+	troll2 := map[string]interface{}{"station": "troll", "temp": 4.0, "time": "t2"}
+	troll3 := map[string]interface{}{"station": "troll", "temp": "", "time": "t3"}
+	docs := func() []*dataFile {
+		return []*dataFile{{name: "a.csv", data: troll1}, {name: "a.csv", data: zeppelin}, {name: "a.csv", data: troll2}, {name: "b.csv", data: troll3}}
+	}
+
+	g := NewGrouper(newTestContext(map[string]interface{}{"group": `{"keys": ["station"]}`}))
+	want := []map[string]interface{}{
+		{"station": "troll", "rows": []interface{}{troll1, troll2}},
+		{"station": "zeppelin", "rows": []interface{}{zeppelin}},
+		{"station": "troll", "rows": []interface{}{troll3}},
+	}
+	if got := runStage(g.stage, docs()...); !reflect.DeepEqual(got, want) {
+		t.Errorf("per file: got %v, want %v", got, want)
+	}
+
+	g = NewGrouper(newTestContext(map[string]interface{}{"group": `{"keys": ["station"], "scope": "run", "rows": "", "aggregates": [
+		{"key": "temp", "op": "mean"}, {"key": "time", "op": "last", "target": "end"}, {"op": "count"}]}`}))
+	want = []map[string]interface{}{
+		{"station": "troll", "temp_mean": 3.0, "end": "t3", "count": 3.0},
+		{"station": "zeppelin", "temp_mean": -1.0, "end": "t1", "count": 1.0},
+	}
+	if got := runStage(g.stage, docs()...); !reflect.DeepEqual(got, want) {
+		t.Errorf("per run: got %v, want %v", got, want)
+	}
+
+	for _, spec := range []string{`{}`, `{"keys": ["a"], "aggregates": [{"key": "b", "op": "median"}]}`, `{"keys": ["a"], "aggregates": [{"op": "max"}]}`} {
+		if g := NewGrouper(newTestContext(map[string]interface{}{"group": spec})); g.err == nil {
+			t.Errorf("%s: expected a spec error", spec)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ghostdoc
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codegangsta/cli"
+	"github.com/npolar/ghostdoc/context"
+)
+
+// newTestContext returns a context where values are the configured flags,
+// every other flag has its zero value
+func newTestContext(values map[string]interface{}) context.GhostContext {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	return context.NewOverlayContext(context.NewCliContext(cli.NewContext(nil, set, nil)), values)
+}
+
+// writeTestFile writes content to name in a temporary directory and returns the path
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// parseInput runs the parser strategy over input and returns the documents
+func parseInput(s ParserStrategy, name, input string) []*dataFile {
+	dataChan := make(chan *dataFile)
+	go func() {
+		s.parse(&rawFile{name: name, reader: ioutil.NopCloser(strings.NewReader(input))}, dataChan)
+		close(dataChan)
+	}()
+
+	var docs []*dataFile
+	for data := range dataChan {
+		docs = append(docs, data)
+	}
+	return docs
+}
+
+// runStage sends docs through the stage and returns the documents it emits
+func runStage(s stage, docs ...*dataFile) []map[string]interface{} {
+	in := make(chan *dataFile, len(docs))
+	for _, doc := range docs {
+		in <- doc
+	}
+	close(in)
+
+	var got []map[string]interface{}
+	for data := range s(in) {
+		got = append(got, data.data)
+	}
+	return got
+}
@@ -9,7 +9,7 @@ import (
 func JSONCommand() cli.Command {
 	return cli.Command{
 		Name:  "json",
-		Usage: "parse json files",
+		Usage: "parse json files (object, array or newline delimited)",
 		Flags: []cli.Flag{
 			cli.BoolFlag{
 				Name:  "bulk, b",
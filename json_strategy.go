@@ -1,15 +1,19 @@
 package ghostdoc
 
 import (
+	"bufio"
 	"encoding/json"
+	"io"
 	"regexp"
+	"unicode"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/npolar/ghostdoc/context"
 )
 
 const (
-	jsonFileRegex = `(?i)^.+\.json|geojson|topojson$`
+	jsonFileRegex  = `(?i)^.+\.json|geojson|topojson|ndjson|jsonl$`
+	jsonMediaRegex = `(?i)^(application|text)/(([a-z0-9.-]+\+)?json|x-ndjson|jsonl)$`
 )
 
 // JSONStrategy typedef
@@ -35,20 +39,77 @@ func (j *JSONStrategy) isSupportedFile(filename string) bool {
 	return jsonFile.MatchString(filename)
 }
 
+// isSupportedMediaType returns true if the content type of a remote input is supported
+func (j *JSONStrategy) isSupportedMediaType(mediaType string) bool {
+	jsonMedia := regexp.MustCompile(jsonMediaRegex)
+	return jsonMedia.MatchString(mediaType)
+}
+
 func (j *JSONStrategy) getContext() context.GhostContext {
 	return j.context
 }
 
+// parse streams the documents of a json array, a single object or newline
+// delimited json (NDJSON) onto the data channel one at a time
 func (j *JSONStrategy) parse(rawFile *rawFile, dataChan chan *dataFile) {
-	var jsonData interface{}
+	reader := bufio.NewReader(rawFile.reader)
+	first, err := j.firstChar(reader)
+	decoder := json.NewDecoder(reader)
+	row := 0
 
-	if err := json.Unmarshal(rawFile.data, &jsonData); err == nil {
-		dataChan <- &dataFile{
-			name: rawFile.name,
-			data: jsonData.(map[string]interface{}),
+	if err == nil && first == '[' {
+		if _, err = decoder.Token(); err == nil {
+			for err == nil && decoder.More() {
+				row++
+				err = j.decodeDocument(rawFile, row, decoder, dataChan)
+			}
+		}
+		if err == nil {
+			_, err = decoder.Token()
 		}
-
 	} else {
+		for err == nil {
+			row++
+			err = j.decodeDocument(rawFile, row, decoder, dataChan)
+		}
+	}
+
+	if err != nil && err != io.EOF {
 		log.Error("[JSON] Parsing error!", err)
 	}
 }
+
+// decodeDocument decodes the next document, row is its position in the array or stream
+func (j *JSONStrategy) decodeDocument(rawFile *rawFile, row int, decoder *json.Decoder, dataChan chan *dataFile) error {
+	var jsonData interface{}
+	err := decoder.Decode(&jsonData)
+
+	if err == nil {
+		if doc, ok := jsonData.(map[string]interface{}); ok {
+			dataChan <- &dataFile{
+				name:   rawFile.name,
+				data:   doc,
+				source: rawFile,
+				row:    row,
+			}
+		} else {
+			log.Warn("[JSON] Skipping non object document: ", jsonData)
+		}
+	}
+
+	return err
+}
+
+// firstChar returns the first non whitespace char without consuming it
+func (j *JSONStrategy) firstChar(reader *bufio.Reader) (byte, error) {
+	for {
+		char, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(char[0])) {
+			return char[0], nil
+		}
+		reader.ReadByte()
+	}
+}
@@ -0,0 +1,33 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONStrategyParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []interface{}
+		rows []int
+	}{
+		{` [{"a": 1}, {"a": 2}]`, []interface{}{1.0, 2.0}, []int{1, 2}},
+		{"\n\t{\"a\": 1}", []interface{}{1.0}, []int{1}},
+		{"{\"a\": 1}\n{\"a\": 2}\n{\"a\": 3}\n", []interface{}{1.0, 2.0, 3.0}, []int{1, 2, 3}},
+		{`[1, {"a": 2}, "x", {"a": 4}]`, []interface{}{2.0, 4.0}, []int{2, 4}},
+		{"{\"a\": 1}\n{\"a\": ", []interface{}{1.0}, []int{1}},
+		{"  ", nil, nil},
+	}
+
+	for _, test := range tests {
+		var got []interface{}
+		var rows []int
+		for _, data := range parseInput(NewJSONStrategy(newTestContext(nil)), "test.json", test.in) {
+			got = append(got, data.data["a"])
+			rows = append(rows, data.row)
+		}
+		if !reflect.DeepEqual(got, test.want) || !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%q: got %v on rows %v, want %v on rows %v", test.in, got, rows, test.want, test.rows)
+		}
+	}
+}
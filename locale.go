@@ -0,0 +1,113 @@
+package ghostdoc
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/npolar/ghostdoc/context"
+)
+
+const (
+	unicodeMinus = "\u2212"
+)
+
+// numberLocale rewrites numbers written with a decimal comma, thousands
+// separators or the unicode minus sign to the plain form (-1234.5) that the
+// type inference understands. Other values are left untouched.
+type numberLocale struct {
+	decimal   string
+	thousands []string
+	minus     bool
+	number    *regexp.Regexp
+}
+
+// newNumberLocale returns nil when no locale options are set. A thousands
+// separator that is also the decimal separator is rejected, 12.5 could be
+// read either way
+func newNumberLocale(c context.GhostContext) (*numberLocale, error) {
+	l := &numberLocale{decimal: ".", minus: c.Bool("unicode-minus")}
+	if c.Bool("decimal-comma") {
+		l.decimal = ","
+	}
+
+	for _, sep := range strings.Split(c.String("thousands"), "") {
+		if sep == l.decimal {
+			return nil, errors.New("[Locale Error] The thousands separator " + sep + " is also the decimal separator")
+		}
+		l.thousands = append(l.thousands, sep)
+		// Spaces in exports are often no-break or narrow no-break spaces
+		if sep == " " {
+			l.thousands = append(l.thousands, "\u00a0", "\u202f")
+		}
+	}
+
+	if l.decimal == "." && len(l.thousands) == 0 && !l.minus {
+		return nil, nil
+	}
+
+	var seps []string
+	for _, sep := range l.thousands {
+		seps = append(seps, regexp.QuoteMeta(sep))
+	}
+	integer := `\d+`
+	if len(seps) > 0 {
+		integer = `(?:\d{1,3}(?:(?:` + strings.Join(seps, "|") + `)\d{3})+|\d+)`
+	}
+	l.number = regexp.MustCompile(`^[-+]?` + integer + `(?:` + regexp.QuoteMeta(l.decimal) + `\d+)?(?:[eE][-+]?\d+)?$`)
+	return l, nil
+}
+
+// normalize returns the plain form of a locale number and true, or the value and false
+func (l *numberLocale) normalize(value string) (string, bool) {
+	text := strings.TrimSpace(value)
+	minus := l.minus && strings.HasPrefix(text, unicodeMinus)
+	if minus {
+		text = "-" + strings.TrimPrefix(text, unicodeMinus)
+	}
+	if !l.number.MatchString(text) || !minus && !strings.ContainsAny(text, l.localeChars()) {
+		return value, false
+	}
+
+	for _, sep := range l.thousands {
+		text = strings.Replace(text, sep, "", -1)
+	}
+	return strings.Replace(text, l.decimal, ".", 1), true
+}
+
+// localeChars are the chars that differ from a plain number
+func (l *numberLocale) localeChars() string {
+	chars := strings.Join(l.thousands, "")
+	if l.decimal != "." {
+		chars += l.decimal
+	}
+	return chars
+}
+
+// ambiguous returns true for values that are not numbers in the configured
+// format but would be read as plain numbers, e.g. 12.5 with a decimal comma
+func (l *numberLocale) ambiguous(value string) bool {
+	text := strings.TrimSpace(value)
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return false
+	}
+	chars := strings.Join(l.thousands, "")
+	if l.decimal != "." {
+		chars += "."
+	}
+	return strings.ContainsAny(text, chars)
+}
+
+// normalizeRecord rewrites the locale numbers of a csv record in place and
+// returns the positions of ambiguous values
+func (l *numberLocale) normalizeRecord(record []string) []int {
+	var ambiguous []int
+	for i, field := range record {
+		var ok bool
+		if record[i], ok = l.normalize(field); !ok && l.ambiguous(field) {
+			ambiguous = append(ambiguous, i)
+		}
+	}
+	return ambiguous
+}
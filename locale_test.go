@@ -0,0 +1,160 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNumberLocaleNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		in     string
+		want   string
+		ok     bool
+	}{
+		{
+			name:   "decimal comma",
+			values: map[string]interface{}{"decimal-comma": true},
+			in:     "12,5",
+			want:   "12.5",
+			ok:     true,
+		},
+		{
+			name:   "plain number is left alone",
+			values: map[string]interface{}{"decimal-comma": true},
+			in:     "12",
+			want:   "12",
+		},
+		{
+			name:   "space thousands",
+			values: map[string]interface{}{"decimal-comma": true, "thousands": " "},
+			in:     "1 234 567,5",
+			want:   "1234567.5",
+			ok:     true,
+		},
+		{
+			name:   "no-break space thousands",
+			values: map[string]interface{}{"decimal-comma": true, "thousands": " "},
+			in:     "1\u00a0234,5",
+			want:   "1234.5",
+			ok:     true,
+		},
+		{
+			name:   "narrow no-break space thousands",
+			values: map[string]interface{}{"thousands": " "},
+			in:     "1\u202f234",
+			want:   "1234",
+			ok:     true,
+		},
+		{
+			name:   "dot thousands with decimal comma",
+			values: map[string]interface{}{"decimal-comma": true, "thousands": "."},
+			in:     "1.234,5",
+			want:   "1234.5",
+			ok:     true,
+		},
+		{
+			name:   "dot without groups of three is text",
+			values: map[string]interface{}{"decimal-comma": true, "thousands": "."},
+			in:     "12.5",
+			want:   "12.5",
+		},
+		{
+			name:   "unicode minus",
+			values: map[string]interface{}{"unicode-minus": true},
+			in:     "\u221212.5",
+			want:   "-12.5",
+			ok:     true,
+		},
+		{
+			name:   "text",
+			values: map[string]interface{}{"decimal-comma": true},
+			in:     "12,5 m",
+			want:   "12,5 m",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := newNumberLocale(newTestContext(test.values))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := l.normalize(test.in)
+			if got != test.want || ok != test.ok {
+				t.Errorf("got %q %v, want %q %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestNewNumberLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		isNil  bool
+		err    bool
+	}{
+		{name: "no options", values: map[string]interface{}{}, isNil: true},
+		{name: "dot thousands and dot decimal", values: map[string]interface{}{"thousands": "."}, isNil: true, err: true},
+		{name: "comma thousands and comma decimal", values: map[string]interface{}{"thousands": ",", "decimal-comma": true}, isNil: true, err: true},
+		{name: "comma thousands", values: map[string]interface{}{"thousands": ","}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := newNumberLocale(newTestContext(test.values))
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if (l == nil) != test.isNil {
+				t.Errorf("got locale %v, want nil %v", l, test.isNil)
+			}
+		})
+	}
+}
+
+func TestNumberLocaleAmbiguous(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		record []string
+		want   []string
+		flags  []int
+	}{
+		{
+			name:   "dot in a decimal comma format",
+			values: map[string]interface{}{"decimal-comma": true, "thousands": "."},
+			record: []string{"1.234,5", "12.5", "12", "v1.2"},
+			want:   []string{"1234.5", "12.5", "12", "v1.2"},
+			flags:  []int{1},
+		},
+		{
+			name:   "decimal comma without thousands",
+			values: map[string]interface{}{"decimal-comma": true},
+			record: []string{"12,5", "12.5"},
+			want:   []string{"12.5", "12.5"},
+			flags:  []int{1},
+		},
+		{
+			name:   "plain decimals with comma thousands",
+			values: map[string]interface{}{"thousands": ","},
+			record: []string{"1,234.5", "12.5", "1,5"},
+			want:   []string{"1234.5", "12.5", "1,5"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := newNumberLocale(newTestContext(test.values))
+			if err != nil {
+				t.Fatal(err)
+			}
+			flags := l.normalizeRecord(test.record)
+			if !reflect.DeepEqual(test.record, test.want) || !reflect.DeepEqual(flags, test.flags) {
+				t.Errorf("got %q flagged %v, want %q flagged %v", test.record, flags, test.want, test.flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package ghostdoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/npolar/ciface"
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+const (
+	lookupJSONRegex = `(?i)\.(json|geojson)$`
+	lookupKeySep    = "\x1f"
+)
+
+// lookupSpec is the --lookup configuration
+type lookupSpec struct {
+	File      string   `json:"file"`
+	Delimiter string   `json:"delimiter"`
+	Keys      []string `json:"keys"`
+	On        []string `json:"on"`
+	Columns   []string `json:"columns"`
+	Prefix    string   `json:"prefix"`
+	Target    string   `json:"target"`
+	Miss      string   `json:"miss"`
+}
+
+// Lookup joins the rows of a csv or json lookup table to documents on one
+// or more key fields. The table is loaded once and indexed on the join keys.
+type Lookup struct {
+	context context.GhostContext
+	spec    lookupSpec
+	index   map[string]map[string]interface{}
+	err     error
+}
+
+// NewLookup factory
+func NewLookup(c context.GhostContext) *Lookup {
+	l := &Lookup{context: c}
+	if spec := c.GlobalString("lookup"); spec != "" {
+		if l.err = util.ReadConfig(spec, &l.spec); l.err == nil {
+			l.err = l.load()
+		}
+	}
+	return l
+}
+
+// load reads the table and builds the index
+func (l *Lookup) load() error {
+	if l.spec.File == "" || len(l.spec.Keys) == 0 {
+		return errors.New("lookup needs a file and keys")
+	}
+	if len(l.spec.On) == 0 {
+		l.spec.On = l.spec.Keys
+	}
+	if len(l.spec.On) != len(l.spec.Keys) {
+		return errors.New("lookup keys and on need the same number of fields")
+	}
+
+	rows, err := l.readTable()
+	if err != nil {
+		return fmt.Errorf("%s: %v", l.spec.File, err)
+	}
+
+	l.index = make(map[string]map[string]interface{})
+	for _, row := range rows {
+		if key, ok := l.joinKey(row, l.spec.On); ok {
+			l.index[key] = row
+		}
+	}
+	return nil
+}
+
+func (l *Lookup) readTable() ([]map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(l.spec.File)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := NewDecoder(l.context)
+	if err == nil {
+		raw, err = decoder.decodeBytes(raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if regexp.MustCompile(lookupJSONRegex).MatchString(l.spec.File) {
+		err = json.Unmarshal(raw, &rows)
+		return rows, err
+	}
+
+	cif := ciface.NewParser(raw)
+	if l.spec.Delimiter != "" {
+		cif.Reader.Comma, _, _, _ = strconv.UnquoteChar(l.spec.Delimiter, '"')
+	}
+
+	docs, err := cif.Parse()
+	for _, doc := range docs {
+		rows = append(rows, doc.(map[string]interface{}))
+	}
+	return rows, err
+}
+
+// joinKey builds the index key from the values at paths
+func (l *Lookup) joinKey(data map[string]interface{}, paths []string) (string, bool) {
+	values := make([]string, len(paths))
+	for i, path := range paths {
+		value, ok := util.GetPath(data, path)
+		if !ok || value == nil {
+			return "", false
+		}
+		values[i] = toText(value)
+	}
+	return strings.Join(values, lookupKeySep), true
+}
+
+func (l *Lookup) enrich(data map[string]interface{}) (map[string]interface{}, error) {
+	if l.err != nil {
+		return data, errors.New("lookup: " + l.err.Error())
+	}
+	if l.index == nil {
+		return data, nil
+	}
+
+	key, ok := l.joinKey(data, l.spec.Keys)
+	row, found := l.index[key]
+	if !ok || !found {
+		switch l.spec.Miss {
+		case "drop":
+			return nil, nil
+		case "error":
+			return data, fmt.Errorf("lookup: no match for %v = %q", l.spec.Keys, strings.Replace(key, lookupKeySep, ",", -1))
+		}
+		return data, nil
+	}
+
+	for column, value := range l.columns(row) {
+		if err := util.SetPath(data, l.targetPath(column), copyValue(value)); err != nil {
+			return data, errors.New("lookup: " + err.Error())
+		}
+	}
+	return data, nil
+}
+
+// columns returns the configured columns of the row, or all but the join columns
+func (l *Lookup) columns(row map[string]interface{}) map[string]interface{} {
+	columns := make(map[string]interface{})
+	if len(l.spec.Columns) > 0 {
+		for _, column := range l.spec.Columns {
+			columns[column], _ = util.GetPath(row, column)
+		}
+		return columns
+	}
+
+	for column, value := range row {
+		columns[column] = value
+	}
+	for _, column := range l.spec.On {
+		delete(columns, column)
+	}
+	return columns
+}
+
+// copyValue deep copies the json objects and arrays of a table value, the
+// rows are shared by all documents and must not be changed through them
+func copyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(typed))
+		for key, child := range typed {
+			object[key] = copyValue(child)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(typed))
+		for i, child := range typed {
+			array[i] = copyValue(child)
+		}
+		return array
+	}
+	return value
+}
+
+func (l *Lookup) targetPath(column string) string {
+	if l.spec.Target != "" {
+		return l.spec.Target + "." + l.spec.Prefix + column
+	}
+	return l.spec.Prefix + column
+}
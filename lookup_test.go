@@ -0,0 +1,82 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupEnrich(t *testing.T) {
+	table := writeTestFile(t, "stations.json", `[{"id": "1", "name": "Ny-Ålesund"}, {"id": "2", "name": "Troll"}]`)
+	tests := []struct {
+		name string
+		spec string
+		in   map[string]interface{}
+		want map[string]interface{}
+		err  bool
+	}{
+		{
+			name: "match",
+			spec: `{"file": "` + table + `", "keys": ["station"], "on": ["id"]}`,
+			in:   map[string]interface{}{"station": "2"},
+			want: map[string]interface{}{"station": "2", "name": "Troll"},
+		},
+		{
+			name: "target and prefix",
+			spec: `{"file": "` + table + `", "keys": ["station"], "on": ["id"], "target": "meta", "prefix": "station_"}`,
+			in:   map[string]interface{}{"station": "1"},
+			want: map[string]interface{}{"station": "1", "meta": map[string]interface{}{"station_name": "Ny-Ålesund"}},
+		},
+		{
+			name: "miss keeps the document",
+			spec: `{"file": "` + table + `", "keys": ["station"], "on": ["id"]}`,
+			in:   map[string]interface{}{"station": "3"},
+			want: map[string]interface{}{"station": "3"},
+		},
+		{
+			name: "miss drop",
+			spec: `{"file": "` + table + `", "keys": ["station"], "on": ["id"], "miss": "drop"}`,
+			in:   map[string]interface{}{"station": "3"},
+			want: nil,
+		},
+		{
+			name: "miss error",
+			spec: `{"file": "` + table + `", "keys": ["station"], "on": ["id"], "miss": "error"}`,
+			in:   map[string]interface{}{"station": "3"},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := NewLookup(newTestContext(map[string]interface{}{"lookup": test.spec}))
+			if l.err != nil {
+				t.Fatal(l.err)
+			}
+			got, err := l.enrich(test.in)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if !test.err && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLookupCopiesTableValues(t *testing.T) {
+	table := writeTestFile(t, "stations.json", `[{"id": "1", "site": {"name": "Ny-Ålesund", "tags": ["arctic"]}}]`)
+	l := NewLookup(newTestContext(map[string]interface{}{"lookup": `{"file": "` + table + `", "keys": ["station"], "on": ["id"]}`}))
+	if l.err != nil {
+		t.Fatal(l.err)
+	}
+
+	first, _ := l.enrich(map[string]interface{}{"station": "1"})
+	first["site"].(map[string]interface{})["name"] = "changed"
+	first["site"].(map[string]interface{})["tags"].([]interface{})[0] = "changed"
+
+	second, _ := l.enrich(map[string]interface{}{"station": "1"})
+	want := map[string]interface{}{"station": "1", "site": map[string]interface{}{"name": "Ny-Ålesund", "tags": []interface{}{"arctic"}}}
+	if !reflect.DeepEqual(second, want) {
+		t.Errorf("got %v, want %v", second, want)
+	}
+}
@@ -41,17 +41,41 @@ func configureFlags() []cli.Flag {
 			Value: 2,
 			Usage: "Specify the number of concurrent operations",
 		},
+		cli.StringFlag{
+			Name:  "encoding, enc",
+			Usage: "Convert input from this encoding to UTF-8 before parsing [auto|utf-8|utf-16|latin1|windows-1252|<IANA name>]",
+		},
 		cli.StringFlag{
 			Name:  "exclude, e",
-			Usage: "Specify keys (before mapping) to exclude in the output",
+			Usage: "Specify keys or key paths (a.b.0 or /a/b/0) before mapping to exclude in the output",
 		},
 		cli.StringFlag{
 			Name:  "filename, f",
 			Usage: "Set filename to use in name-pattern when piping data via stdin",
 		},
+		cli.StringFlag{
+			Name:  "flatten, fn",
+			Usage: "Flatten nested objects and arrays into keys joined by this separator, e.g. . or _",
+		},
+		cli.StringFlag{
+			Name:  "from-list, fl",
+			Usage: "Read input paths from a list file, use - to read the list from stdin",
+		},
+		cli.BoolFlag{
+			Name:  "null, 0",
+			Usage: "Input paths in the --from-list are separated by null chars (find -print0)",
+		},
+		cli.StringFlag{
+			Name:  "geometry, geo",
+			Usage: "Build a GeoJSON geometry from latitude and longitude fields using a JSON/YAML spec, see README",
+		},
+		cli.StringFlag{
+			Name:  "group, gr",
+			Usage: "Group documents by key fields into one document per group using a JSON/YAML spec, see README",
+		},
 		cli.StringFlag{
 			Name:  "include, i",
-			Usage: "Specify keys (before mapping) to include in the output",
+			Usage: "Specify keys or key paths (a.b.0 or /a/b/0) before mapping to include in the output",
 		},
 		cli.StringFlag{
 			Name:  "js, j",
@@ -64,12 +88,20 @@ func configureFlags() []cli.Flag {
 		},
 		cli.StringFlag{
 			Name:  "key-map, k",
-			Usage: "Sets mapping file to use to rename headers/keys. JSON Format {\"oldkey\": \"newkey\"}",
+			Usage: "Sets mapping file to use to rename headers/keys or key paths. JSON Format {\"oldkey\": \"new.key\"}",
+		},
+		cli.StringFlag{
+			Name:  "lookup, lu",
+			Usage: "Join rows from a csv/json lookup table on key fields using a JSON/YAML spec, see README",
 		},
 		cli.StringFlag{
 			Name:  "merge, m",
 			Usage: "Specify additional JSON data to inject into the output.",
 		},
+		cli.StringFlag{
+			Name:  "missing, mv",
+			Usage: "Replace sentinel values with null or remove them and check required fields using a JSON/YAML spec, see README",
+		},
 		cli.StringFlag{
 			Name:  "name-pattern, n",
 			Usage: "Set pattern file to extract filename info and inject it into the result",
@@ -83,6 +115,10 @@ func configureFlags() []cli.Flag {
 			Value: "data",
 			Usage: "Specify the key to use for the payload when wrapping",
 		},
+		cli.StringFlag{
+			Name:  "pipeline, pl",
+			Usage: "Run the mappers in the order defined in a JSON or YAML pipeline file",
+		},
 		cli.StringFlag{
 			Name:  "log-file, lf",
 			Usage: "Log to file instead of stdout",
@@ -96,6 +132,26 @@ func configureFlags() []cli.Flag {
 			Name:  "log-mail, lm",
 			Usage: "Forward log errors to email",
 		},
+		cli.StringFlag{
+			Name:  "template, tp",
+			Usage: "Set fields from Go text/template expressions over the document, e.g. '{\"title\": \"{{.station}} {{.date | year}}\"}'",
+		},
+		cli.StringFlag{
+			Name:  "track, tr",
+			Usage: "Derive time delta, distance, speed and bearing between consecutive fixes using a JSON/YAML spec, see README",
+		},
+		cli.StringFlag{
+			Name:  "transform, tf",
+			Usage: "Apply value operations (cast, trim, replace, round, ...) from a JSON/YAML spec, see README",
+		},
+		cli.StringFlag{
+			Name:  "dates, dt",
+			Usage: "Parse date fields and normalize them to RFC 3339 using a JSON/YAML spec, see README",
+		},
+		cli.StringFlag{
+			Name:  "unflatten, uf",
+			Usage: "Expand keys joined by this separator into nested objects, e.g. . for position.lat",
+		},
 		cli.BoolFlag{
 			Name:  "uuid, u",
 			Usage: "Injects a namesaced uuid with the 'id' key",
@@ -109,6 +165,14 @@ func configureFlags() []cli.Flag {
 			Value: "id",
 			Usage: "uuid key name",
 		},
+		cli.StringFlag{
+			Name:  "where",
+			Usage: "Only publish documents matching the expression, e.g. 'MsgType == 30 && IridCEP < 5'",
+		},
+		cli.StringFlag{
+			Name:  "within, wi",
+			Usage: "Only publish documents inside a bounding box or GeoJSON polygon using a JSON/YAML spec, see README",
+		},
 		cli.StringFlag{
 			Name:  "wrapper, w",
 			Usage: "Define JSON wrapper a wrapper for the payload",
@@ -117,6 +181,26 @@ func configureFlags() []cli.Flag {
 			Name:  "recursive, r",
 			Usage: "Recursive read mode. also process sub-dirs",
 		},
+		cli.StringFlag{
+			Name:  "privacy, pr",
+			Usage: "Redact, truncate or HMAC pseudonymize sensitive fields using a JSON/YAML spec, see README",
+		},
+		cli.StringFlag{
+			Name:  "provenance, pv",
+			Usage: "Inject the source file, path, row, sha256, ingest time, version and flags under this key",
+		},
+		cli.StringFlag{
+			Name:  "qc",
+			Usage: "Add QARTOD quality control flags from a JSON/YAML rules file, see README",
+		},
+		cli.StringFlag{
+			Name:  "reproject, rp",
+			Usage: "Reproject coordinate fields or a geometry between EPSG:4326, 3413, 3575 and UTM 33N/35N using a JSON/YAML spec, see README",
+		},
+		cli.StringFlag{
+			Name:  "reshape, rs",
+			Usage: "Melt channel columns into one document per channel or pivot them back using a JSON/YAML spec, see README",
+		},
 		cli.StringFlag{
 			Name:  "schema, s",
 			Usage: "Reference to a JSON Schema to validate json output against",
@@ -129,6 +213,7 @@ func defineCommands() []cli.Command {
 		ghostdoc.CsvCommand(),
 		ghostdoc.JSONCommand(),
 		ghostdoc.TextCommand(),
+		ghostdoc.ExportCommand(),
 	}
 
 }
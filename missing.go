@@ -0,0 +1,157 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// missingSpec is the --missing configuration
+type missingSpec struct {
+	Values    []interface{}            `json:"values"`
+	Fields    map[string][]interface{} `json:"fields"`
+	Action    string                   `json:"action"`
+	Required  []string                 `json:"required"`
+	OnMissing string                   `json:"on_missing"`
+}
+
+// MissingValues replaces sentinel values (-9999, NaN, NA, "") with null or
+// removes their keys. Global values apply to every key, nested ones included,
+// field values only to their key path. Required fields that are absent or
+// null afterwards are logged or reject the document.
+type MissingValues struct {
+	context context.GhostContext
+	spec    missingSpec
+	err     error
+}
+
+// NewMissingValues factory
+func NewMissingValues(c context.GhostContext) *MissingValues {
+	m := &MissingValues{context: c}
+	if spec := c.GlobalString("missing"); spec != "" {
+		if m.err = util.ReadConfig(spec, &m.spec); m.err == nil {
+			m.err = m.compile()
+		}
+	}
+	return m
+}
+
+func (m *MissingValues) compile() error {
+	switch m.spec.Action {
+	case "":
+		m.spec.Action = "null"
+	case "null", "remove":
+	default:
+		return fmt.Errorf("action is null or remove, not %q", m.spec.Action)
+	}
+
+	switch m.spec.OnMissing {
+	case "":
+		m.spec.OnMissing = "warn"
+	case "warn", "reject":
+	default:
+		return fmt.Errorf("on_missing is warn or reject, not %q", m.spec.OnMissing)
+	}
+	return nil
+}
+
+func (m *MissingValues) normalize(data map[string]interface{}) (map[string]interface{}, error) {
+	if m.err != nil {
+		return data, errors.New("missing: " + m.err.Error())
+	}
+
+	var keys []string
+	for key, sentinels := range m.spec.Fields {
+		if value, ok := util.GetPath(data, key); ok && m.isSentinel(value, sentinels) {
+			keys = append(keys, key)
+		}
+	}
+	m.replace(data, keys)
+	if len(m.spec.Values) > 0 {
+		m.replaceAll(data)
+	}
+
+	var missing []string
+	for _, key := range m.spec.Required {
+		if value, ok := util.GetPath(data, key); !ok || value == nil {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		err := fmt.Errorf("missing: required fields missing: %s", strings.Join(missing, ", "))
+		if m.spec.OnMissing == "reject" {
+			return data, err
+		}
+		log.Warn(err.Error())
+	}
+	return data, nil
+}
+
+func (m *MissingValues) replace(data map[string]interface{}, keys []string) {
+	if m.spec.Action == "remove" {
+		util.DeletePaths(data, keys)
+		return
+	}
+	for _, key := range keys {
+		util.SetPath(data, key, nil)
+	}
+}
+
+// isMissing returns true when value is a global sentinel or a sentinel of key
+func (m *MissingValues) isMissing(key string, value interface{}) bool {
+	return m.isSentinel(value, m.spec.Values) || m.isSentinel(value, m.spec.Fields[key])
+}
+
+// replaceAll walks the document for global sentinels. Array elements are set
+// to null since removing them would shift the positions
+func (m *MissingValues) replaceAll(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if m.isSentinel(child, m.spec.Values) {
+				if m.spec.Action == "remove" {
+					delete(typed, key)
+				} else {
+					typed[key] = nil
+				}
+			} else {
+				m.replaceAll(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range typed {
+			if m.isSentinel(child, m.spec.Values) {
+				typed[i] = nil
+			} else {
+				m.replaceAll(child)
+			}
+		}
+	}
+}
+
+// isSentinel compares numerically when both sides are numbers, so -9999
+// also matches "-9999.0", and treats NaN as equal to NaN
+func (m *MissingValues) isSentinel(value interface{}, sentinels []interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return false
+	}
+
+	for _, sentinel := range sentinels {
+		if number, ok := toNumber(value); ok && math.IsNaN(number) {
+			if sentinelNumber, ok := toNumber(sentinel); ok && math.IsNaN(sentinelNumber) {
+				return true
+			}
+			continue
+		}
+		if equalValues(value, sentinel) {
+			return true
+		}
+	}
+	return false
+}
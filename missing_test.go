@@ -0,0 +1,46 @@
+package ghostdoc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMissingValues(t *testing.T) {
+	tests := []struct {
+		spec string
+		in   map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			`{"values": [-9999, "NaN"]}`,
+			map[string]interface{}{"a": -9999.0, "b": "-9999.0", "c": math.NaN(), "d": 1.0},
+			map[string]interface{}{"a": nil, "b": nil, "c": nil, "d": 1.0},
+		},
+		{
+			`{"values": [-9999], "action": "remove"}`,
+			map[string]interface{}{"a": map[string]interface{}{"b": -9999.0}, "d": []interface{}{-9999.0, 2.0}},
+			map[string]interface{}{"a": map[string]interface{}{}, "d": []interface{}{nil, 2.0}},
+		},
+		{
+			`{"fields": {"pos.depth": [0]}, "action": "remove"}`,
+			map[string]interface{}{"count": 0.0, "pos": map[string]interface{}{"depth": 0.0}},
+			map[string]interface{}{"count": 0.0, "pos": map[string]interface{}{}},
+		},
+	}
+
+	for _, test := range tests {
+		m := NewMissingValues(newTestContext(map[string]interface{}{"missing": test.spec}))
+		if got, err := m.normalize(test.in); err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, %v, want %v", test.spec, got, err, test.want)
+		}
+	}
+
+	m := NewMissingValues(newTestContext(map[string]interface{}{"missing": `{"values": [-9999], "required": ["a"], "on_missing": "reject"}`}))
+	if _, err := m.normalize(map[string]interface{}{"a": -9999.0}); err == nil {
+		t.Error("expected a required value to reject the document")
+	}
+	if m := NewMissingValues(newTestContext(map[string]interface{}{"missing": `{"action": "drop"}`})); m.err == nil {
+		t.Error("expected a spec error")
+	}
+}
@@ -1,7 +1,11 @@
 package ghostdoc
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -14,6 +18,7 @@ type Parser struct {
 	context context.GhostContext
 	ParserStrategy
 	argumentHandler *ArgumentHandler
+	decoder         *Decoder
 	rawChan         chan *rawFile
 	dataChan        chan *dataFile
 }
@@ -25,6 +30,11 @@ func NewParser(parserStrategy ParserStrategy) *Parser {
 	parser := &Parser{context: context, ParserStrategy: parserStrategy, rawChan: rawChan}
 	parser.argumentHandler = NewArgumentHandler(parser, rawChan)
 	util.ConfigureLogger(context)
+
+	var err error
+	if parser.decoder, err = NewDecoder(context); err != nil {
+		panic(err.Error())
+	}
 	return parser
 }
 
@@ -39,6 +49,7 @@ func (p *Parser) process() {
 
 	p.listen()
 	writerWaitGroup.Wait()
+	writer.summary.log()
 
 	util.SendErrorMail()
 	log.Info("Stop, took: ", time.Now().Sub(start))
@@ -49,7 +60,7 @@ func (p *Parser) listen() {
 		p.argumentHandler.processArguments()
 		go func() {
 			for rawFile := range p.rawChan {
-				p.parse(rawFile, p.dataChan)
+				p.parseFile(rawFile)
 			}
 			close(p.dataChan)
 		}()
@@ -57,3 +68,34 @@ func (p *Parser) listen() {
 		fmt.Println(err)
 	}
 }
+
+// parseFile decodes and parses the raw file onto the data channel. Local
+// files that need a checksum are hashed in a pass before they are parsed
+func (p *Parser) parseFile(raw *rawFile) {
+	if raw.checksum {
+		if sum, err := hashFile(raw.path); err == nil {
+			raw.sha256 = sum
+		} else {
+			log.WithFields(log.Fields{"input": raw.name}).Warn("[File Error] ", err)
+		}
+	}
+
+	raw.reader = p.decoder.decode(raw.reader)
+	p.parse(raw, p.dataChan)
+	raw.reader.Close()
+}
+
+// hashFile returns the hex sha256 of the file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
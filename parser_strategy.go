@@ -7,5 +7,6 @@ type ParserStrategy interface {
 	getContext() context.GhostContext
 	isRawInput(string) bool
 	isSupportedFile(string) bool
+	isSupportedMediaType(string) bool
 	parse(*rawFile, chan *dataFile)
 }
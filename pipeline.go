@@ -0,0 +1,123 @@
+package ghostdoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/npolar/ghostdoc/context"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	stepKey = "step"
+)
+
+// defaultPipeline is the mapper order used when no pipeline file is configured
+var defaultPipeline = []string{"where", "include", "exclude", "key-map", "unflatten", "missing", "transform", "dates", "lookup", "geometry", "within", "reproject", "privacy", "template", "merge", "flatten", "wrap", "uuid", "provenance", "js"}
+
+// Pipeline is an ordered list of mapper steps read from a JSON or YAML file.
+// Every step names a mapper and can hold configuration using the same keys
+// as the global flags, anything not configured falls back on the flags.
+type Pipeline struct {
+	context context.GhostContext
+	steps   []map[string]interface{}
+}
+
+// NewPipeline factory
+func NewPipeline(c context.GhostContext) (*Pipeline, error) {
+	pipeline := &Pipeline{context: c}
+	file := c.GlobalString("pipeline")
+
+	if file == "" {
+		for _, name := range defaultPipeline {
+			pipeline.steps = append(pipeline.steps, map[string]interface{}{stepKey: name})
+		}
+		return pipeline, nil
+	}
+
+	raw, err := ioutil.ReadFile(file)
+	if err == nil {
+		// YAML is a superset of JSON so both formats are read with the yaml parser
+		err = yaml.Unmarshal(raw, &pipeline.steps)
+	}
+	if err != nil {
+		err = errors.New("[Pipeline Error] " + err.Error())
+	}
+
+	return pipeline, err
+}
+
+// mappers builds the mapper for each step. Steps without configuration use the
+// components of w, configured steps run on a Writer where the step configuration
+// overrides the global flags. Steps with the same configuration share a Writer
+// so every component is loaded once
+func (p *Pipeline) mappers(w *Writer) ([]metaMapper, error) {
+	var mappers []metaMapper
+	writers := make(map[string]*Writer)
+
+	for i, step := range p.steps {
+		name, _ := step[stepKey].(string)
+		stepWriter, err := p.stepWriter(w, writers, name, step)
+		if err != nil {
+			return nil, fmt.Errorf("[Pipeline Error] Step %d (%s): %v", i+1, name, err)
+		}
+
+		if fn, ok := stepWriter.mapper(name); ok {
+			mappers = append(mappers, fn)
+		} else {
+			return nil, fmt.Errorf("[Pipeline Error] Unknown step %d: %v", i+1, step[stepKey])
+		}
+	}
+
+	return mappers, nil
+}
+
+// has reports if the pipeline contains a step with name
+func (p *Pipeline) has(name string) bool {
+	for _, step := range p.steps {
+		if step[stepKey] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stepWriter returns the Writer a step runs on, writers caches them by step configuration
+func (p *Pipeline) stepWriter(w *Writer, writers map[string]*Writer, name string, step map[string]interface{}) (*Writer, error) {
+	values := p.stepValues(name, step)
+	if len(values) == 0 {
+		return w, nil
+	}
+
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	key := name + string(raw)
+
+	if stepWriter, ok := writers[key]; ok {
+		return stepWriter, nil
+	}
+
+	stepWriter, err := w.newStepWriter(context.NewOverlayContext(p.context, values), name)
+	writers[key] = stepWriter
+	return stepWriter, err
+}
+
+// stepValues returns the step configuration with the defaults that are implied
+// by listing the step in a pipeline
+func (p *Pipeline) stepValues(name string, step map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	if name == "uuid" {
+		values["uuid"] = true
+	}
+
+	for key, val := range step {
+		if key != stepKey {
+			values[key] = val
+		}
+	}
+	return values
+}
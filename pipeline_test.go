@@ -0,0 +1,147 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipelineMappers(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline string
+		flags    map[string]interface{}
+		in       map[string]interface{}
+		want     map[string]interface{}
+		err      string
+	}{
+		{
+			name:     "steps run in order with their own configuration",
+			pipeline: "- step: key-map\n  key-map: '{\"a\": \"b\"}'\n- step: transform\n  transform: '{\"b\": [{\"op\": \"upper\"}]}'\n",
+			in:       map[string]interface{}{"a": "x"},
+			want:     map[string]interface{}{"b": "X"},
+		},
+		{
+			name:     "unconfigured steps fall back on the flags",
+			pipeline: "- step: transform\n",
+			flags:    map[string]interface{}{"transform": `{"a": [{"op": "lower"}]}`},
+			in:       map[string]interface{}{"a": "X"},
+			want:     map[string]interface{}{"a": "x"},
+		},
+		{
+			name:     "unknown step",
+			pipeline: "- step: nope\n",
+			err:      "Unknown step 1",
+		},
+		{
+			name:     "step configuration errors fail at startup",
+			pipeline: "- step: transform\n  transform: '{\"a\": [{\"op\": \"nope\"}]}'\n",
+			err:      "Step 1 (transform)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := map[string]interface{}{"pipeline": writeTestFile(t, "pipeline.yml", test.pipeline)}
+			for key, val := range test.flags {
+				flags[key] = val
+			}
+			w := NewWriter(newTestContext(flags), nil)
+
+			err := w.buildPipeline()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := w.applyMappers(test.in, &docMeta{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPipelineSharesWriters(t *testing.T) {
+	steps := "- step: js\n- step: transform\n  transform: '{\"a\": [{\"op\": \"upper\"}]}'\n- step: transform\n  transform: '{\"a\": [{\"op\": \"upper\"}]}'\n- step: transform\n  transform: '{\"a\": [{\"op\": \"lower\"}]}'\n"
+	c := newTestContext(map[string]interface{}{"pipeline": writeTestFile(t, "pipeline.yml", steps)})
+	w := NewWriter(c, nil)
+	pipeline, err := NewPipeline(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writers := make(map[string]*Writer)
+	var got []*Writer
+	for _, step := range pipeline.steps {
+		stepWriter, err := pipeline.stepWriter(w, writers, step[stepKey].(string), step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, stepWriter)
+	}
+
+	if got[0] != w {
+		t.Error("unconfigured step should use the components of the writer")
+	}
+	if got[1] != got[2] {
+		t.Error("steps with the same configuration should share a writer")
+	}
+	if got[1] == got[3] {
+		t.Error("steps with different configuration should not share a writer")
+	}
+	if got[1].Lookup != w.Lookup || got[1].Validator != w.Validator {
+		t.Error("step writers should share the components of other steps")
+	}
+}
+
+func TestPipelineValidates(t *testing.T) {
+	tests := []struct {
+		pipeline string
+		want     bool
+	}{
+		{"- step: js\n", false},
+		{"- step: js\n- step: validate\n", true},
+	}
+
+	for _, test := range tests {
+		w := NewWriter(newTestContext(map[string]interface{}{"pipeline": writeTestFile(t, "pipeline.yml", test.pipeline)}), nil)
+		if err := w.buildPipeline(); err != nil {
+			t.Fatal(err)
+		}
+		if w.validates != test.want {
+			t.Errorf("%q: validates %v, want %v", test.pipeline, w.validates, test.want)
+		}
+	}
+}
+
+func TestWriterCheck(t *testing.T) {
+	tests := []struct {
+		flags map[string]interface{}
+		err   string
+	}{
+		{map[string]interface{}{}, ""},
+		{map[string]interface{}{"transform": `{"a": [{"op": "nope"}]}`}, "transform"},
+		{map[string]interface{}{"where": "a =="}, "where"},
+		{map[string]interface{}{"lookup": `{"file": "/does/not/exist.csv", "keys": ["a"]}`}, "lookup"},
+		{map[string]interface{}{"schema": "file:///does/not/exist.json"}, "schema"},
+	}
+
+	for _, test := range tests {
+		err := NewWriter(newTestContext(test.flags), nil).check()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%v: unexpected error %v", test.flags, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%v: got error %v, want %q", test.flags, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ghostdoc
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/npolar/ghostdoc/context"
+)
+
+var (
+	runStart = time.Now().UTC()
+	hostOnce sync.Once
+	hostname string
+)
+
+// resolvePlaceholders executes the {{...}} placeholders in the string values
+// of a wrapper or merge object as text/templates over the document. On top of
+// the template step functions it offers env, capture (name-pattern captures)
+// and run (timestamp, hostname, version, file).
+func resolvePlaceholders(c context.GhostContext, value interface{}, data map[string]interface{}, meta *docMeta) (interface{}, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			resolved, err := resolvePlaceholders(c, child, data, meta)
+			if err != nil {
+				return value, err
+			}
+			typed[key] = resolved
+		}
+	case []interface{}:
+		for i, child := range typed {
+			resolved, err := resolvePlaceholders(c, child, data, meta)
+			if err != nil {
+				return value, err
+			}
+			typed[i] = resolved
+		}
+	case string:
+		if strings.Contains(typed, "{{") {
+			return executePlaceholder(c, typed, data, meta)
+		}
+	}
+	return value, nil
+}
+
+func executePlaceholder(c context.GhostContext, text string, data map[string]interface{}, meta *docMeta) (string, error) {
+	tmpl, err := template.New("placeholder").Option("missingkey=error").Funcs(templateFuncs).Funcs(template.FuncMap{
+		"env": os.Getenv,
+		"capture": func(i int) string {
+			if meta != nil && i >= 0 && i < len(meta.captures) {
+				return meta.captures[i]
+			}
+			return ""
+		},
+		"run": func(key string) string {
+			return runValue(c, key, meta)
+		},
+	}).Parse(text)
+	if err != nil {
+		return text, err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	return buf.String(), err
+}
+
+// runValue returns the run metadata, the ingest timestamp is the start of the run
+func runValue(c context.GhostContext, key string, meta *docMeta) string {
+	switch key {
+	case "timestamp":
+		return runStart.Format(time.RFC3339)
+	case "hostname":
+		hostOnce.Do(func() { hostname, _ = os.Hostname() })
+		return hostname
+	case "version":
+		return c.Cli().App.Version
+	case "file":
+		if meta != nil {
+			return meta.name
+		}
+	}
+	return ""
+}
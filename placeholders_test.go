@@ -0,0 +1,32 @@
+package ghostdoc
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestResolvePlaceholders(t *testing.T) {
+	os.Setenv("GHOSTDOC_TEST_SITE", "ny-alesund")
+	defer os.Unsetenv("GHOSTDOC_TEST_SITE")
+
+	meta := &docMeta{name: "2016/station_troll.csv", captures: []string{"station_troll", "troll"}}
+	value := map[string]interface{}{
+		"count": 1.0,
+		"ref":   "{{.id | upper}}",
+		"list":  []interface{}{"{{env \"GHOSTDOC_TEST_SITE\"}}", "{{capture 1}}", "{{run \"file\"}}"},
+	}
+	want := map[string]interface{}{
+		"count": 1.0,
+		"ref":   "A1",
+		"list":  []interface{}{"ny-alesund", "troll", "2016/station_troll.csv"},
+	}
+
+	got, err := resolvePlaceholders(newTestContext(nil), value, map[string]interface{}{"id": "a1"}, meta)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+	if _, err := resolvePlaceholders(newTestContext(nil), "{{.station}}", map[string]interface{}{}, meta); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+}
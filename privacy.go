@@ -0,0 +1,220 @@
+package ghostdoc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// privacyField configures the masking of a single field
+type privacyField struct {
+	Key    string      `json:"key"`
+	Op     string      `json:"op"`
+	Target string      `json:"target"`
+	With   interface{} `json:"with"`
+	Digits int         `json:"digits"`
+	Length int         `json:"length"`
+}
+
+// privacySpec is the --privacy configuration
+type privacySpec struct {
+	KeyEnv  string          `json:"key_env"`
+	KeyFile string          `json:"key_file"`
+	Fields  []*privacyField `json:"fields"`
+}
+
+// Privacy masks sensitive fields before they are published. Fields are
+// redacted (replaced), truncated (numbers to digits decimals, text to length
+// chars when set) or pseudonymized with an HMAC-SHA256, which stays the same between
+// runs as long as the key does. Arrays are masked element by element.
+type Privacy struct {
+	context context.GhostContext
+	spec    privacySpec
+	key     []byte
+	err     error
+}
+
+// NewPrivacy factory
+func NewPrivacy(c context.GhostContext) *Privacy {
+	p := &Privacy{context: c}
+	if spec := c.GlobalString("privacy"); spec != "" {
+		if p.err = util.ReadConfig(spec, &p.spec); p.err == nil {
+			p.err = p.compile()
+		}
+	}
+	return p
+}
+
+func (p *Privacy) compile() error {
+	hmacKey := false
+	for _, field := range p.spec.Fields {
+		if field.Key == "" {
+			return errors.New("privacy fields need a key")
+		}
+		switch field.Op {
+		case "redact":
+		case "truncate":
+			if field.Digits < 0 || field.Length < 0 {
+				return fmt.Errorf("%s: digits and length can not be negative", field.Key)
+			}
+		case "hmac":
+			hmacKey = true
+		default:
+			return fmt.Errorf("%s: unknown op %q", field.Key, field.Op)
+		}
+		if field.Target == "" {
+			field.Target = field.Key
+		}
+		if wildcards(field.Target) != wildcards(field.Key) {
+			return fmt.Errorf("%s: target %s needs the same number of * as the key", field.Key, field.Target)
+		}
+	}
+
+	if hmacKey {
+		return p.readKey()
+	}
+	return nil
+}
+
+// readKey reads the hmac key from the environment or a file, it is never
+// part of the spec so the spec can be shared
+func (p *Privacy) readKey() error {
+	switch {
+	case p.spec.KeyEnv != "":
+		p.key = []byte(os.Getenv(p.spec.KeyEnv))
+	case p.spec.KeyFile != "":
+		raw, err := ioutil.ReadFile(p.spec.KeyFile)
+		if err != nil {
+			return err
+		}
+		p.key = []byte(strings.TrimRight(string(raw), "\r\n"))
+	default:
+		return errors.New("hmac needs a key_env or key_file")
+	}
+
+	if len(p.key) == 0 {
+		return errors.New("hmac key is empty")
+	}
+	return nil
+}
+
+func (p *Privacy) mask(data map[string]interface{}) (map[string]interface{}, error) {
+	if p.err != nil {
+		return data, errors.New("privacy: " + p.err.Error())
+	}
+
+	for _, field := range p.spec.Fields {
+		matches, err := util.ExpandPath(data, field.Key)
+		if err != nil {
+			return data, fmt.Errorf("privacy: %s: %v", field.Key, err)
+		}
+
+		var moved []string
+		for _, segments := range matches {
+			path := util.JoinPath(segments)
+			value, _ := util.GetPath(data, path)
+			if value == nil {
+				continue
+			}
+
+			masked := field.With
+			if field.Op != "redact" {
+				if masked, err = p.maskValue(field, value); err != nil {
+					return data, fmt.Errorf("privacy: %s: %v", path, err)
+				}
+			}
+
+			target := util.JoinPath(p.target(field, segments))
+			if target != path {
+				moved = append(moved, path)
+			}
+			if err := util.SetPath(data, target, masked); err != nil {
+				return data, errors.New("privacy: " + err.Error())
+			}
+		}
+		util.DeletePaths(data, moved)
+	}
+	return data, nil
+}
+
+// target returns the target segments of a matched key, with the * of the
+// target replaced by the elements the key matched
+func (p *Privacy) target(field *privacyField, segments []string) []string {
+	if field.Target == field.Key {
+		return segments
+	}
+
+	var captures []string
+	for i, segment := range util.SplitPath(field.Key) {
+		if segment == "*" {
+			captures = append(captures, segments[i])
+		}
+	}
+
+	target := util.SplitPath(field.Target)
+	for i, segment := range target {
+		if segment == "*" {
+			target[i], captures = captures[0], captures[1:]
+		}
+	}
+	return target
+}
+
+func (p *Privacy) maskValue(field *privacyField, value interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return nil, fmt.Errorf("can not %s an object, use the key paths of its fields", field.Op)
+	case []interface{}:
+		masked := make([]interface{}, len(typed))
+		for i, item := range typed {
+			var err error
+			if masked[i], err = p.maskValue(field, item); err != nil {
+				return nil, err
+			}
+		}
+		return masked, nil
+	}
+
+	if field.Op == "hmac" {
+		mac := hmac.New(sha256.New, p.key)
+		mac.Write([]byte(toText(value)))
+		sum := hex.EncodeToString(mac.Sum(nil))
+		if field.Length > 0 && field.Length < len(sum) {
+			sum = sum[:field.Length]
+		}
+		return sum, nil
+	}
+
+	// Truncate numbers towards zero, so a position never moves to a neighbouring cell
+	if number, ok := value.(float64); ok {
+		scale := math.Pow(10, float64(field.Digits))
+		return math.Trunc(number*scale) / scale, nil
+	}
+	text := []rune(toText(value))
+	if field.Length > 0 && field.Length < len(text) {
+		text = text[:field.Length]
+	}
+	return string(text), nil
+}
+
+// wildcards counts the * segments of a key path
+func wildcards(path string) int {
+	count := 0
+	for _, segment := range util.SplitPath(path) {
+		if segment == "*" {
+			count++
+		}
+	}
+	return count
+}
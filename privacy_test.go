@@ -0,0 +1,118 @@
+package ghostdoc
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrivacyMask(t *testing.T) {
+	os.Setenv("GHOSTDOC_TEST_HMAC_KEY", "secret")
+	defer os.Unsetenv("GHOSTDOC_TEST_HMAC_KEY")
+
+	// echo -n "a@example.org" | openssl dgst -sha256 -hmac secret
+	const mac = "4476d4c9"
+	tests := []struct {
+		name   string
+		fields string
+		in     map[string]interface{}
+		want   map[string]interface{}
+		err    string
+	}{
+		{
+			name:   "redact",
+			fields: `[{"key": "phone", "op": "redact", "with": "[redacted]"}, {"key": "name", "op": "redact"}]`,
+			in:     map[string]interface{}{"phone": "123", "name": "x"},
+			want:   map[string]interface{}{"phone": "[redacted]", "name": nil},
+		},
+		{
+			name:   "truncate numbers and text",
+			fields: `[{"key": "geometry.coordinates", "op": "truncate", "digits": 2}, {"key": "zip", "op": "truncate", "length": 2}]`,
+			in:     map[string]interface{}{"geometry": map[string]interface{}{"coordinates": []interface{}{15.6789, -78.2345}}, "zip": "9170"},
+			want:   map[string]interface{}{"geometry": map[string]interface{}{"coordinates": []interface{}{15.67, -78.23}}, "zip": "91"},
+		},
+		{
+			name:   "truncate keeps text without a length",
+			fields: `[{"key": "lat", "op": "truncate", "digits": 1}]`,
+			in:     map[string]interface{}{"lat": "78.2345 N"},
+			want:   map[string]interface{}{"lat": "78.2345 N"},
+		},
+		{
+			name:   "hmac to target",
+			fields: `[{"key": "observer.email", "op": "hmac", "target": "observer.id", "length": 8}]`,
+			in:     map[string]interface{}{"observer": map[string]interface{}{"email": "a@example.org"}},
+			want:   map[string]interface{}{"observer": map[string]interface{}{"id": mac}},
+		},
+		{
+			name:   "array wildcard",
+			fields: `[{"key": "rows.*.email", "op": "redact"}]`,
+			in:     map[string]interface{}{"rows": []interface{}{map[string]interface{}{"email": "a"}, map[string]interface{}{"id": 1.0}}},
+			want:   map[string]interface{}{"rows": []interface{}{map[string]interface{}{"email": nil}, map[string]interface{}{"id": 1.0}}},
+		},
+		{
+			name:   "array wildcard to target",
+			fields: `[{"key": "rows.*.email", "op": "hmac", "target": "rows.*.id", "length": 8}]`,
+			in:     map[string]interface{}{"rows": []interface{}{map[string]interface{}{"email": "a@example.org"}, map[string]interface{}{"email": "a@example.org"}}},
+			want:   map[string]interface{}{"rows": []interface{}{map[string]interface{}{"id": mac}, map[string]interface{}{"id": mac}}},
+		},
+		{
+			name:   "missing fields are skipped",
+			fields: `[{"key": "phone", "op": "redact"}, {"key": "rows.*.email", "op": "redact"}]`,
+			in:     map[string]interface{}{"name": "x"},
+			want:   map[string]interface{}{"name": "x"},
+		},
+		{
+			name:   "path under an array",
+			fields: `[{"key": "rows.email", "op": "redact"}]`,
+			in:     map[string]interface{}{"rows": []interface{}{map[string]interface{}{"email": "a"}}},
+			err:    "use * to reach its elements",
+		},
+		{
+			name:   "truncate an object",
+			fields: `[{"key": "observer", "op": "truncate", "length": 2}]`,
+			in:     map[string]interface{}{"observer": map[string]interface{}{"email": "a"}},
+			err:    "can not truncate an object",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := `{"key_env": "GHOSTDOC_TEST_HMAC_KEY", "fields": ` + test.fields + `}`
+			p := NewPrivacy(newTestContext(map[string]interface{}{"privacy": spec}))
+			if p.err != nil {
+				t.Fatal(p.err)
+			}
+			got, err := p.mask(test.in)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrivacySpecErrors(t *testing.T) {
+	tests := []string{
+		`{"fields": [{"op": "redact"}]}`,
+		`{"fields": [{"key": "a", "op": "nope"}]}`,
+		`{"fields": [{"key": "a", "op": "truncate", "digits": -1}]}`,
+		`{"fields": [{"key": "a", "op": "hmac"}]}`,
+		`{"key_env": "GHOSTDOC_TEST_UNSET", "fields": [{"key": "a", "op": "hmac"}]}`,
+		`{"fields": [{"key": "rows.*.a", "op": "redact", "target": "b"}]}`,
+	}
+
+	for _, spec := range tests {
+		if p := NewPrivacy(newTestContext(map[string]interface{}{"privacy": spec})); p.err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ghostdoc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// projection converts geographic coordinates in degrees to projected
+// coordinates in meters and back
+type projection interface {
+	forward(lon, lat float64) (float64, float64)
+	inverse(x, y float64) (float64, float64)
+}
+
+// ellipsoid holds the derived constants of a reference ellipsoid
+type ellipsoid struct {
+	a, f, e, e2 float64
+}
+
+var (
+	wgs84 = newEllipsoid(6378137, 1/298.257223563)
+	grs80 = newEllipsoid(6378137, 1/298.257222101)
+)
+
+func newEllipsoid(a, f float64) ellipsoid {
+	e2 := f * (2 - f)
+	return ellipsoid{a: a, f: f, e: math.Sqrt(e2), e2: e2}
+}
+
+// projections by EPSG code. A nil projection is geographic
+var projections = map[string]func() projection{
+	"4326":  func() projection { return nil },
+	"3413":  func() projection { return newPolarStereographic(wgs84, 70, -45) },
+	"3575":  func() projection { return newPolarLAEA(wgs84, 10) },
+	"32633": func() projection { return newTransverseMercator(wgs84, 15) },
+	"32635": func() projection { return newTransverseMercator(wgs84, 27) },
+	"25833": func() projection { return newTransverseMercator(grs80, 15) },
+	"25835": func() projection { return newTransverseMercator(grs80, 27) },
+}
+
+// lookupProjection accepts EPSG codes as "EPSG:3413" or "3413"
+func lookupProjection(code string) (projection, error) {
+	key := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(code)), "EPSG:")
+	if factory, ok := projections[key]; ok {
+		return factory(), nil
+	}
+	return nil, fmt.Errorf("unsupported crs %q", code)
+}
+
+// polarStereographic is the north polar stereographic projection with a
+// latitude of true scale (Snyder, Map Projections, p. 160)
+type polarStereographic struct {
+	ellipsoid
+	lon0, scale float64
+}
+
+func newPolarStereographic(el ellipsoid, latTS, lon0 float64) *polarStereographic {
+	phi := latTS * math.Pi / 180
+	m := math.Cos(phi) / math.Sqrt(1-el.e2*math.Sin(phi)*math.Sin(phi))
+	p := &polarStereographic{ellipsoid: el, lon0: lon0 * math.Pi / 180}
+	p.scale = el.a * m / p.t(phi)
+	return p
+}
+
+func (p *polarStereographic) t(phi float64) float64 {
+	es := p.e * math.Sin(phi)
+	return math.Tan(math.Pi/4-phi/2) / math.Pow((1-es)/(1+es), p.e/2)
+}
+
+func (p *polarStereographic) forward(lon, lat float64) (float64, float64) {
+	rho := p.scale * p.t(lat*math.Pi/180)
+	lambda := lon*math.Pi/180 - p.lon0
+	return rho * math.Sin(lambda), -rho * math.Cos(lambda)
+}
+
+func (p *polarStereographic) inverse(x, y float64) (float64, float64) {
+	t := math.Hypot(x, y) / p.scale
+	phi := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		es := p.e * math.Sin(phi)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-es)/(1+es), p.e/2))
+		if math.Abs(next-phi) < 1e-12 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+	return normalizeLon((p.lon0 + math.Atan2(x, -y)) * 180 / math.Pi), phi * 180 / math.Pi
+}
+
+// polarLAEA is the north polar Lambert azimuthal equal-area projection
+// (Snyder, Map Projections, p. 187)
+type polarLAEA struct {
+	ellipsoid
+	lon0, qp float64
+}
+
+func newPolarLAEA(el ellipsoid, lon0 float64) *polarLAEA {
+	p := &polarLAEA{ellipsoid: el, lon0: lon0 * math.Pi / 180}
+	p.qp = p.q(math.Pi / 2)
+	return p
+}
+
+func (p *polarLAEA) q(phi float64) float64 {
+	s := math.Sin(phi)
+	es := p.e * s
+	return (1 - p.e2) * (s/(1-es*es) - math.Log((1-es)/(1+es))/(2*p.e))
+}
+
+func (p *polarLAEA) forward(lon, lat float64) (float64, float64) {
+	rho := p.a * math.Sqrt(math.Max(p.qp-p.q(lat*math.Pi/180), 0))
+	lambda := lon*math.Pi/180 - p.lon0
+	return rho * math.Sin(lambda), -rho * math.Cos(lambda)
+}
+
+// inverse converts the authalic latitude back with the series of Snyder (3-18)
+func (p *polarLAEA) inverse(x, y float64) (float64, float64) {
+	rho := math.Hypot(x, y)
+	q := p.qp - rho*rho/(p.a*p.a)
+	beta := math.Asin(math.Max(-1, math.Min(1, q/p.qp)))
+	e4, e6 := p.e2*p.e2, p.e2*p.e2*p.e2
+	phi := beta +
+		(p.e2/3+31*e4/180+517*e6/5040)*math.Sin(2*beta) +
+		(23*e4/360+251*e6/3780)*math.Sin(4*beta) +
+		(761*e6/45360)*math.Sin(6*beta)
+	return normalizeLon((p.lon0 + math.Atan2(x, -y)) * 180 / math.Pi), phi * 180 / math.Pi
+}
+
+// transverseMercator is UTM (north) using the Krüger series to the fourth
+// order in n, accurate to well below a millimeter within the zone
+type transverseMercator struct {
+	ellipsoid
+	lon0, k0, falseEasting, radius float64
+	alpha, beta, delta             [4]float64
+}
+
+func newTransverseMercator(el ellipsoid, lon0 float64) *transverseMercator {
+	n := el.f / (2 - el.f)
+	n2, n3, n4 := n*n, n*n*n, n*n*n*n
+	return &transverseMercator{
+		ellipsoid:    el,
+		lon0:         lon0 * math.Pi / 180,
+		k0:           0.9996,
+		falseEasting: 500000,
+		radius:       el.a / (1 + n) * (1 + n2/4 + n4/64),
+		alpha:        [4]float64{n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180, 13*n2/48 - 3*n3/5 + 557*n4/1440, 61*n3/240 - 103*n4/140, 49561 * n4 / 161280},
+		beta:         [4]float64{n/2 - 2*n2/3 + 37*n3/96 - n4/360, n2/48 + n3/15 - 437*n4/1440, 17*n3/480 - 37*n4/840, 4397 * n4 / 161280},
+		delta:        [4]float64{2*n - 2*n2/3 - 2*n3 + 116*n4/45, 7*n2/3 - 8*n3/5 - 227*n4/45, 56*n3/15 - 136*n4/35, 4279 * n4 / 630},
+	}
+}
+
+func (p *transverseMercator) forward(lon, lat float64) (float64, float64) {
+	phi, lambda := lat*math.Pi/180, lon*math.Pi/180-p.lon0
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - p.e*math.Atanh(p.e*math.Sin(phi)))
+	xi0 := math.Atan2(t, math.Cos(lambda))
+	eta0 := math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+
+	xi, eta := xi0, eta0
+	for j, alpha := range p.alpha {
+		k := 2 * float64(j+1)
+		xi += alpha * math.Sin(k*xi0) * math.Cosh(k*eta0)
+		eta += alpha * math.Cos(k*xi0) * math.Sinh(k*eta0)
+	}
+	return p.falseEasting + p.k0*p.radius*eta, p.k0 * p.radius * xi
+}
+
+func (p *transverseMercator) inverse(x, y float64) (float64, float64) {
+	xi := y / (p.k0 * p.radius)
+	eta := (x - p.falseEasting) / (p.k0 * p.radius)
+
+	xi0, eta0 := xi, eta
+	for j, beta := range p.beta {
+		k := 2 * float64(j+1)
+		xi0 -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta0 -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	chi := math.Asin(math.Sin(xi0) / math.Cosh(eta0))
+	phi := chi
+	for j, delta := range p.delta {
+		phi += delta * math.Sin(2*float64(j+1)*chi)
+	}
+	lambda := p.lon0 + math.Atan2(math.Sinh(eta0), math.Cos(xi0))
+	return normalizeLon(lambda * 180 / math.Pi), phi * 180 / math.Pi
+}
+
+// normalizeLon wraps a longitude into [-180, 180]
+func normalizeLon(lon float64) float64 {
+	for lon > 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	return lon
+}
@@ -0,0 +1,54 @@
+package ghostdoc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestProjection(t *testing.T) {
+	// EPSG Guidance Note 7-2 polar stereographic (variant B) example, mirrored to the north
+	x, y := newPolarStereographic(wgs84, 71, 70).forward(120, 75)
+	if math.Abs(x-1255380.79) > 0.01 || math.Abs(y+1053389.56) > 0.01 {
+		t.Errorf("polar stereographic: got %.3f, %.3f", x, y)
+	}
+	if x, y := newTransverseMercator(wgs84, 15).forward(15, 0); math.Abs(x-500000) > 1e-6 || math.Abs(y) > 1e-6 {
+		t.Errorf("utm origin: got %.3f, %.3f", x, y)
+	}
+
+	for _, code := range []string{"3413", "3575", "32633", "25835"} {
+		p, err := lookupProjection(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lon, lat := p.inverse(p.forward(15.6, 78.2))
+		if math.Abs(lon-15.6) > 1e-7 || math.Abs(lat-78.2) > 1e-7 {
+			t.Errorf("EPSG:%s: 15.6, 78.2 came back as %v, %v", code, lon, lat)
+		}
+	}
+
+	if p, err := lookupProjection(" epsg:4326 "); p != nil || err != nil {
+		t.Errorf("EPSG:4326: got %v, %v, want no projection", p, err)
+	}
+	if _, err := lookupProjection("EPSG:900913"); err == nil {
+		t.Error("expected an error for an unsupported crs")
+	}
+}
+
+func TestReproject(t *testing.T) {
+	r := NewReprojector(newTestContext(map[string]interface{}{"reproject": `{"from": "4326", "to": "3413", "geometry": "geometry"}`}))
+	got, err := r.reproject(map[string]interface{}{"geometry": map[string]interface{}{"type": "Point", "coordinates": []interface{}{100.0, 90.0}}})
+	want := map[string]interface{}{"geometry": map[string]interface{}{"type": "Point", "coordinates": []interface{}{0.0, 0.0}}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want the pole at the origin", got, err)
+	}
+
+	r = NewReprojector(newTestContext(map[string]interface{}{"reproject": `{"from": "4326", "to": "32633", "x": "lon", "y": "lat", "target_x": "east", "target_y": "north"}`}))
+	got, err = r.reproject(map[string]interface{}{"lon": 15.0, "lat": "0"})
+	if err != nil || math.Abs(got["east"].(float64)-500000) > 0.01 || math.Abs(got["north"].(float64)) > 0.01 {
+		t.Errorf("got %v, %v, want the utm origin", got, err)
+	}
+	if _, err := r.reproject(map[string]interface{}{"lon": 15.0, "lat": 91.0}); err == nil {
+		t.Error("expected an error for a latitude out of range")
+	}
+}
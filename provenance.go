@@ -0,0 +1,75 @@
+package ghostdoc
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/npolar/ghostdoc/util"
+)
+
+// injectProvenance sets the origin of the document at the --provenance key:
+// the input file, its absolute path (or url), the row (csv line, json
+// element), the SHA-256 of disk files, the ingest timestamp, the ghostdoc
+// version, the command and the flags that were set
+func (w *Writer) injectProvenance(data map[string]interface{}, meta *docMeta) (map[string]interface{}, error) {
+	key := w.context.GlobalString("provenance")
+	if key == "" {
+		return data, nil
+	}
+
+	provenance := map[string]interface{}{
+		"file":     meta.name,
+		"ingested": runValue(w.context, "timestamp", meta),
+		"version":  runValue(w.context, "version", meta),
+		"strategy": w.context.Cli().Command.Name,
+		"flags":    w.setFlags(),
+	}
+	if source := meta.source; source != nil {
+		if source.path != "" {
+			provenance["path"] = source.path
+		}
+		if source.sha256 != "" {
+			provenance["sha256"] = source.sha256
+		}
+	}
+	if meta.row > 0 {
+		provenance["row"] = meta.row
+	}
+
+	if err := util.SetPath(data, key, provenance); err != nil {
+		return data, errors.New("injectProvenance: " + err.Error())
+	}
+	return data, nil
+}
+
+// secretFlags are left out of the provenance, they hold the mail settings
+// and the key locations of the privacy spec
+var secretFlags = map[string]bool{"log-mail": true, "privacy": true}
+
+// setFlags returns the global and command flags set on the command line,
+// without secret flags and with the credentials removed from urls
+func (w *Writer) setFlags() map[string]interface{} {
+	flags := make(map[string]interface{})
+	for _, name := range w.context.GlobalFlagNames() {
+		if w.context.GlobalIsSet(name) && !secretFlags[name] {
+			flags[name] = redactURL(w.context.GlobalString(name))
+		}
+	}
+	for _, name := range w.context.FlagNames() {
+		if w.context.IsSet(name) && !secretFlags[name] {
+			flags[name] = redactURL(w.context.String(name))
+		}
+	}
+	return flags
+}
+
+// redactURL removes the user info and the query, which can carry tokens, from url values
+func redactURL(value string) string {
+	uri, err := url.Parse(value)
+	if err != nil || uri.Scheme == "" || uri.Host == "" {
+		return value
+	}
+	uri.User = nil
+	uri.RawQuery = ""
+	return uri.String()
+}
@@ -0,0 +1,382 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// QARTOD flag values
+const (
+	qcPass         = 1
+	qcNotEvaluated = 2
+	qcSuspect      = 3
+	qcFail         = 4
+	qcMissing      = 9
+)
+
+// qcLimits holds the suspect and fail thresholds of a test
+type qcLimits struct {
+	Suspect float64 `json:"suspect"`
+	Fail    float64 `json:"fail"`
+}
+
+// qcRange holds the suspect (user) and fail (sensor) ranges of the gross range test
+type qcRange struct {
+	Suspect []float64 `json:"suspect"`
+	Fail    []float64 `json:"fail"`
+}
+
+// qcStuck holds the flat line test settings. Suspect and fail are the number
+// of previous values within tolerance of the current value
+type qcStuck struct {
+	Suspect   int     `json:"suspect"`
+	Fail      int     `json:"fail"`
+	Tolerance float64 `json:"tolerance"`
+}
+
+// qcRule configures the tests of a single field
+type qcRule struct {
+	Flag  string    `json:"flag"`
+	Range *qcRange  `json:"range"`
+	Spike *qcLimits `json:"spike"`
+	Stuck *qcStuck  `json:"stuck"`
+	Rate  *qcLimits `json:"rate"`
+}
+
+// qcSpec is the --qc configuration
+type qcSpec struct {
+	Suffix     string             `json:"suffix"`
+	Flag       string             `json:"flag"`
+	Group      []string           `json:"group"`
+	Time       string             `json:"time"`
+	TimeFormat string             `json:"time_format"`
+	Fields     map[string]*qcRule `json:"fields"`
+}
+
+// qcPending is a document waiting for the next value of its series (spike test)
+type qcPending struct {
+	data   *dataFile
+	flags  map[string][]int
+	values map[string]float64
+	prev   map[string]float64
+}
+
+// qcSeries holds the recent values of a series and the time of the last value
+type qcSeries struct {
+	history map[string][]float64
+	times   map[string]time.Time
+	held    *qcPending
+}
+
+// QualityControl annotates sensor values with QARTOD flags (1 pass, 2 not
+// evaluated, 3 suspect, 4 fail, 9 missing) from gross range, spike, flat line
+// (stuck) and rate of change (per second) tests. Documents are never dropped.
+// Series are per input file and optionally split on group keys. The spike test
+// needs the next value, so documents are held back one value in their series.
+// Sentinels of the --missing spec are flagged as missing.
+type QualityControl struct {
+	context context.GhostContext
+	spec    *qcSpec
+	spike   bool
+	dates   *DateNormalizer
+	missing *MissingValues
+	keyMap  map[string]interface{}
+	err     error
+}
+
+// NewQualityControl factory
+func NewQualityControl(c context.GhostContext) *QualityControl {
+	q := &QualityControl{context: c}
+	if spec := c.GlobalString("qc"); spec != "" {
+		q.spec = &qcSpec{}
+		if q.err = util.ReadConfig(spec, q.spec); q.err == nil {
+			q.err = q.compile()
+		}
+		if q.err != nil {
+			q.err = errors.New("[QC Error] " + q.err.Error())
+		}
+	}
+	return q
+}
+
+func (q *QualityControl) compile() error {
+	if len(q.spec.Fields) == 0 {
+		return errors.New("qc needs at least one field rule")
+	}
+	if q.spec.Suffix == "" {
+		q.spec.Suffix = "_qc"
+	}
+
+	for key, rule := range q.spec.Fields {
+		if rule.Flag == "" {
+			rule.Flag = key + q.spec.Suffix
+		}
+		if rule.Range != nil && (len(rule.Range.Fail) != 0 && len(rule.Range.Fail) != 2 || len(rule.Range.Suspect) != 0 && len(rule.Range.Suspect) != 2) {
+			return fmt.Errorf("%s: ranges are [min, max]", key)
+		}
+		if rule.Rate != nil && q.spec.Time == "" {
+			return fmt.Errorf("%s: the rate test needs the time key", key)
+		}
+		q.spike = q.spike || rule.Spike != nil
+	}
+
+	if q.spec.Time == "" {
+		return nil
+	}
+	if q.spec.TimeFormat == "" {
+		q.spec.TimeFormat = time.RFC3339
+	}
+	q.dates = &DateNormalizer{
+		context:  q.context,
+		location: time.UTC,
+		spec:     dateSpec{Fields: []*dateField{{Key: q.spec.Time, Format: q.spec.TimeFormat}}},
+	}
+	return q.dates.compile()
+}
+
+func (q *QualityControl) stage(in chan *dataFile) chan *dataFile {
+	if q.spec == nil {
+		return in
+	}
+
+	out := make(chan *dataFile, cap(in))
+	go func() {
+		var input interface{}
+		series := make(map[string]*qcSeries)
+
+		for data := range in {
+			if data.input() != input {
+				q.flush(series, out)
+				input, series = data.input(), make(map[string]*qcSeries)
+			}
+
+			key, _ := keyValues(data.data, q.spec.Group)
+			s, ok := series[key]
+			if !ok {
+				s = &qcSeries{history: make(map[string][]float64), times: make(map[string]time.Time)}
+				series[key] = s
+			}
+			q.add(s, data, out)
+		}
+
+		q.flush(series, out)
+		close(out)
+	}()
+	return out
+}
+
+// add runs the tests that only need previous values, completes the held
+// document with the spike test and holds this document when spikes are tested
+func (q *QualityControl) add(s *qcSeries, data *dataFile, out chan *dataFile) {
+	pending := &qcPending{
+		data:   data,
+		flags:  make(map[string][]int),
+		values: make(map[string]float64),
+		prev:   make(map[string]float64),
+	}
+	at, timed := q.time(data.data)
+
+	for key, rule := range q.spec.Fields {
+		value, ok := q.value(data.data, key)
+		if !ok {
+			pending.flags[key] = []int{qcMissing}
+			continue
+		}
+
+		history := s.history[key]
+		pending.values[key] = value
+		if len(history) > 0 {
+			pending.prev[key] = history[len(history)-1]
+		}
+		last, lastTimed := s.times[key]
+		rate := qcNotEvaluated
+		if timed && lastTimed {
+			rate = q.rateTest(rule, value, history, at.Sub(last).Seconds())
+		}
+		pending.flags[key] = []int{q.rangeTest(rule, value), rate, q.stuckTest(rule, value, history)}
+		s.history[key] = q.remember(rule, append(history, value))
+		if timed {
+			s.times[key] = at
+		} else {
+			delete(s.times, key)
+		}
+	}
+
+	if s.held != nil {
+		q.spikeTests(s.held, pending.values)
+		q.finish(s.held, out)
+		s.held = nil
+	}
+
+	if q.spike {
+		s.held = pending
+	} else {
+		q.finish(pending, out)
+	}
+}
+
+// flush completes the held documents at the end of a file, without a next value
+func (q *QualityControl) flush(series map[string]*qcSeries, out chan *dataFile) {
+	for _, s := range series {
+		if s.held != nil {
+			q.spikeTests(s.held, nil)
+			q.finish(s.held, out)
+			s.held = nil
+		}
+	}
+}
+
+// finish sets the flag fields and emits the document
+func (q *QualityControl) finish(pending *qcPending, out chan *dataFile) {
+	var all []int
+	for key, rule := range q.spec.Fields {
+		flag := q.aggregate(pending.flags[key])
+		util.SetPath(pending.data.data, rule.Flag, flag)
+		all = append(all, flag)
+	}
+
+	if q.spec.Flag != "" {
+		util.SetPath(pending.data.data, q.spec.Flag, q.aggregate(all))
+	}
+	out <- pending.data
+}
+
+// aggregate returns the worst flag. Missing wins, not evaluated only when nothing was evaluated
+func (q *QualityControl) aggregate(flags []int) int {
+	result := qcNotEvaluated
+	for _, flag := range flags {
+		switch {
+		case flag == qcMissing:
+			return qcMissing
+		case flag == qcNotEvaluated:
+		case result == qcNotEvaluated || flag > result:
+			result = flag
+		}
+	}
+	return result
+}
+
+func (q *QualityControl) rangeTest(rule *qcRule, value float64) int {
+	if rule.Range == nil {
+		return qcNotEvaluated
+	}
+	if r := rule.Range.Fail; len(r) == 2 && (value < r[0] || value > r[1]) {
+		return qcFail
+	}
+	if r := rule.Range.Suspect; len(r) == 2 && (value < r[0] || value > r[1]) {
+		return qcSuspect
+	}
+	return qcPass
+}
+
+// rateTest compares the absolute change per second from the previous value of
+// the series, seconds is the time since that value
+func (q *QualityControl) rateTest(rule *qcRule, value float64, history []float64, seconds float64) int {
+	if rule.Rate == nil || len(history) == 0 || seconds <= 0 {
+		return qcNotEvaluated
+	}
+	return q.limitTest(rule.Rate, math.Abs(value-history[len(history)-1])/seconds)
+}
+
+// stuckTest counts the previous values within tolerance of the value
+func (q *QualityControl) stuckTest(rule *qcRule, value float64, history []float64) int {
+	if rule.Stuck == nil || len(history) == 0 {
+		return qcNotEvaluated
+	}
+
+	same := 0
+	for i := len(history) - 1; i >= 0 && math.Abs(history[i]-value) <= rule.Stuck.Tolerance; i-- {
+		same++
+	}
+
+	switch {
+	case rule.Stuck.Fail > 0 && same >= rule.Stuck.Fail:
+		return qcFail
+	case rule.Stuck.Suspect > 0 && same >= rule.Stuck.Suspect:
+		return qcSuspect
+	}
+	return qcPass
+}
+
+// spikeTests compares the held values with the mean of their neighbours
+func (q *QualityControl) spikeTests(held *qcPending, next map[string]float64) {
+	for key, rule := range q.spec.Fields {
+		if rule.Spike == nil {
+			continue
+		}
+
+		value, ok := held.values[key]
+		prev, prevOk := held.prev[key]
+		nextValue, nextOk := next[key]
+		if !ok || !prevOk || !nextOk {
+			held.flags[key] = append(held.flags[key], qcNotEvaluated)
+			continue
+		}
+		held.flags[key] = append(held.flags[key], q.limitTest(rule.Spike, math.Abs(value-(prev+nextValue)/2)))
+	}
+}
+
+func (q *QualityControl) limitTest(limits *qcLimits, value float64) int {
+	switch {
+	case limits.Fail > 0 && value > limits.Fail:
+		return qcFail
+	case limits.Suspect > 0 && value > limits.Suspect:
+		return qcSuspect
+	}
+	return qcPass
+}
+
+// remember trims the history to what the tests need
+func (q *QualityControl) remember(rule *qcRule, history []float64) []float64 {
+	keep := 1
+	if rule.Stuck != nil && rule.Stuck.Fail+1 > keep {
+		keep = rule.Stuck.Fail + 1
+	}
+	if rule.Stuck != nil && rule.Stuck.Suspect+1 > keep {
+		keep = rule.Stuck.Suspect + 1
+	}
+	if len(history) > keep {
+		history = history[len(history)-keep:]
+	}
+	return history
+}
+
+// time reads the time of the document for the rate test
+func (q *QualityControl) time(data map[string]interface{}) (time.Time, bool) {
+	if q.dates == nil {
+		return time.Time{}, false
+	}
+	value, ok := util.GetPath(data, q.spec.Time)
+	if !ok || value == nil {
+		return time.Time{}, false
+	}
+	at, err := q.dates.parse(q.dates.spec.Fields[0], value, data)
+	return at, err == nil
+}
+
+func (q *QualityControl) value(data map[string]interface{}, key string) (float64, bool) {
+	value, ok := util.GetPath(data, key)
+	if !ok || value == nil || value == "" || q.isMissing(key, value) {
+		return 0, false
+	}
+	number, ok := toNumber(value)
+	return number, ok && !math.IsNaN(number)
+}
+
+// isMissing checks the sentinels of the --missing spec. The qc stage runs
+// before the key-map, the field sentinels are looked up by the key and by the
+// name the key-map gives it
+func (q *QualityControl) isMissing(key string, value interface{}) bool {
+	if q.missing == nil {
+		return false
+	}
+	if mapped, ok := q.keyMap[key].(string); ok && q.missing.isMissing(mapped, value) {
+		return true
+	}
+	return q.missing.isMissing(key, value)
+}
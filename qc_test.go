@@ -0,0 +1,142 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+// runQC runs the qc stage over the values of field a, at the times t if
+// given, and returns the flags of a
+func runQC(t *testing.T, flags map[string]interface{}, values []interface{}, times []string) []interface{} {
+	t.Helper()
+	w := NewWriter(newTestContext(flags), nil)
+	if err := w.check(); err != nil {
+		t.Fatal(err)
+	}
+	if w.QC.err != nil {
+		t.Fatal(w.QC.err)
+	}
+
+	in := make(chan *dataFile, len(values))
+	for i, value := range values {
+		data := map[string]interface{}{"a": value}
+		if i < len(times) {
+			data["t"] = times[i]
+		}
+		in <- &dataFile{name: "test.csv", data: data}
+	}
+	close(in)
+
+	var got []interface{}
+	for data := range w.QC.stage(in) {
+		got = append(got, data.data["a_qc"])
+	}
+	return got
+}
+
+func TestQualityControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		missing string
+		keyMap  string
+		values  []interface{}
+		times   []string
+		want    []interface{}
+	}{
+		{
+			name:   "range",
+			spec:   `{"fields": {"a": {"range": {"fail": [0, 30], "suspect": [5, 25]}}}}`,
+			values: []interface{}{10.0, 3.0, 31.0, -1.0, 25.0},
+			want:   []interface{}{qcPass, qcSuspect, qcFail, qcFail, qcPass},
+		},
+		{
+			name:   "missing values",
+			spec:   `{"fields": {"a": {"range": {"fail": [0, 30]}}}}`,
+			values: []interface{}{nil, "", "n/a", 10.0},
+			want:   []interface{}{qcMissing, qcMissing, qcMissing, qcPass},
+		},
+		{
+			name:    "sentinels of the missing spec",
+			spec:    `{"time": "t", "fields": {"a": {"range": {"fail": [0, 30]}, "rate": {"fail": 0.5}}}}`,
+			missing: `{"values": [-9999]}`,
+			values:  []interface{}{10.0, -9999.0, "-9999", 12.0},
+			times:   []string{"2024-01-01T00:00:00Z", "2024-01-01T00:00:10Z", "2024-01-01T00:00:20Z", "2024-01-01T00:00:30Z"},
+			want:    []interface{}{qcPass, qcMissing, qcMissing, qcPass},
+		},
+		{
+			name:    "field sentinels of the missing spec",
+			spec:    `{"fields": {"a": {"range": {"fail": [0, 30]}}}}`,
+			missing: `{"fields": {"a": [99]}}`,
+			values:  []interface{}{99.0, 10.0},
+			want:    []interface{}{qcMissing, qcPass},
+		},
+		{
+			name:    "field sentinels under the key-map name",
+			spec:    `{"fields": {"a": {"range": {"fail": [0, 30]}}}}`,
+			missing: `{"fields": {"b": [99]}}`,
+			keyMap:  `{"a": "b"}`,
+			values:  []interface{}{99.0, 10.0},
+			want:    []interface{}{qcMissing, qcPass},
+		},
+		{
+			name:   "rate",
+			spec:   `{"time": "t", "fields": {"a": {"rate": {"suspect": 0.5, "fail": 1.5}}}}`,
+			values: []interface{}{10.0, 11.0, 31.0, 36.0, 38.0, 39.0},
+			times:  []string{"2024-01-01T00:00:00Z", "2024-01-01T00:00:10Z", "2024-01-01T00:00:20Z", "2024-01-01T00:00:25Z", "2024-01-01T00:00:45Z", ""},
+			want:   []interface{}{qcNotEvaluated, qcPass, qcFail, qcSuspect, qcPass, qcNotEvaluated},
+		},
+		{
+			name:   "stuck",
+			spec:   `{"fields": {"a": {"stuck": {"suspect": 2, "fail": 3, "tolerance": 0.01}}}}`,
+			values: []interface{}{1.0, 1.0, 1.001, 1.0, 2.0},
+			want:   []interface{}{qcNotEvaluated, qcPass, qcSuspect, qcFail, qcPass},
+		},
+		{
+			name:   "spike",
+			spec:   `{"fields": {"a": {"spike": {"suspect": 1, "fail": 3}}}}`,
+			values: []interface{}{10.0, 10.0, 15.0, 10.0, 11.5, 10.0},
+			want:   []interface{}{qcNotEvaluated, qcSuspect, qcFail, qcFail, qcSuspect, qcNotEvaluated},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := runQC(t, map[string]interface{}{"qc": test.spec, "missing": test.missing, "key-map": test.keyMap}, test.values, test.times)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestQualityControlSeriesPerInput(t *testing.T) {
+	q := NewQualityControl(newTestContext(map[string]interface{}{"qc": `{"fields": {"a": {"stuck": {"fail": 1}}}}`}))
+	first, second := &rawFile{name: "docs.json"}, &rawFile{name: "docs.json"}
+
+	in := make(chan *dataFile, 2)
+	in <- &dataFile{name: "docs.json", source: first, data: map[string]interface{}{"a": 1.0}}
+	in <- &dataFile{name: "docs.json", source: second, data: map[string]interface{}{"a": 1.0}}
+	close(in)
+
+	for data := range q.stage(in) {
+		if data.data["a_qc"] != qcNotEvaluated {
+			t.Errorf("got %v, want a new series for every input", data.data["a_qc"])
+		}
+	}
+}
+
+func TestQualityControlSpecErrors(t *testing.T) {
+	tests := []string{
+		`{"fields": {}}`,
+		`{"fields": {"a": {"range": {"fail": [0]}}}}`,
+		`{"fields": {"a": {"rate": {"fail": 1}}}}`,
+		`{"fields": `,
+	}
+
+	for _, spec := range tests {
+		if q := NewQualityControl(newTestContext(map[string]interface{}{"qc": spec})); q.err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
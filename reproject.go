@@ -0,0 +1,181 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// reprojectSpec is the --reproject configuration
+type reprojectSpec struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	X        string `json:"x"`
+	Y        string `json:"y"`
+	TargetX  string `json:"target_x"`
+	TargetY  string `json:"target_y"`
+	Geometry string `json:"geometry"`
+}
+
+// Reprojector converts coordinates between WGS84 (EPSG:4326) and the polar
+// projections EPSG:3413, EPSG:3575 and UTM 33N/35N (EPSG:32633, 32635, 25833,
+// 25835). Geographic coordinates are lon/lat in degrees, projected x/y in meters.
+type Reprojector struct {
+	context context.GhostContext
+	spec    reprojectSpec
+	from    projection
+	to      projection
+	err     error
+}
+
+// NewReprojector factory
+func NewReprojector(c context.GhostContext) *Reprojector {
+	r := &Reprojector{context: c}
+	if spec := c.GlobalString("reproject"); spec != "" {
+		if r.err = util.ReadConfig(spec, &r.spec); r.err == nil {
+			r.err = r.compile()
+		}
+	}
+	return r
+}
+
+func (r *Reprojector) compile() error {
+	var err error
+	if r.spec.From == "" || r.spec.To == "" {
+		return errors.New("reproject needs both from and to")
+	}
+	if (r.spec.X == "") != (r.spec.Y == "") {
+		return errors.New("reproject needs both x and y")
+	}
+	if r.spec.X == "" && r.spec.Geometry == "" {
+		return errors.New("reproject needs x and y or a geometry")
+	}
+	if r.spec.TargetX == "" {
+		r.spec.TargetX = r.spec.X
+	}
+	if r.spec.TargetY == "" {
+		r.spec.TargetY = r.spec.Y
+	}
+
+	if r.from, err = lookupProjection(r.spec.From); err != nil {
+		return err
+	}
+	r.to, err = lookupProjection(r.spec.To)
+	return err
+}
+
+func (r *Reprojector) reproject(data map[string]interface{}) (map[string]interface{}, error) {
+	if r.err != nil {
+		return data, errors.New("reproject: " + r.err.Error())
+	}
+
+	if r.spec.X != "" {
+		if err := r.fields(data); err != nil {
+			return data, errors.New("reproject: " + err.Error())
+		}
+	}
+
+	if r.spec.Geometry != "" {
+		if geometry, ok := util.GetPath(data, r.spec.Geometry); ok && geometry != nil {
+			if err := r.geometry(geometry); err != nil {
+				return data, fmt.Errorf("reproject: %s: %v", r.spec.Geometry, err)
+			}
+		}
+	}
+	return data, nil
+}
+
+// fields converts the x and y fields into the target fields
+func (r *Reprojector) fields(data map[string]interface{}) error {
+	xValue, xOk := util.GetPath(data, r.spec.X)
+	yValue, yOk := util.GetPath(data, r.spec.Y)
+	if !xOk || !yOk || xValue == nil || yValue == nil || xValue == "" || yValue == "" {
+		return nil
+	}
+
+	x, xOk := toNumber(xValue)
+	y, yOk := toNumber(yValue)
+	if !xOk || !yOk {
+		return fmt.Errorf("%v, %v are not numeric coordinates", xValue, yValue)
+	}
+
+	x, y, err := r.convert(x, y)
+	if err != nil {
+		return err
+	}
+	if err = util.SetPath(data, r.spec.TargetX, x); err != nil {
+		return err
+	}
+	return util.SetPath(data, r.spec.TargetY, y)
+}
+
+// geometry converts the positions of a GeoJSON geometry in place
+func (r *Reprojector) geometry(value interface{}) error {
+	geometry, ok := value.(map[string]interface{})
+	if !ok {
+		return errors.New("is not a GeoJSON geometry")
+	}
+
+	if geometries, ok := geometry["geometries"].([]interface{}); ok {
+		for _, child := range geometries {
+			if err := r.geometry(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return r.positions(geometry["coordinates"])
+}
+
+// positions walks nested coordinate arrays down to [x, y(, z)] positions
+func (r *Reprojector) positions(value interface{}) error {
+	array, ok := value.([]interface{})
+	if !ok || len(array) == 0 {
+		return nil
+	}
+
+	if _, nested := array[0].([]interface{}); nested {
+		for _, child := range array {
+			if err := r.positions(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(array) < 2 {
+		return errors.New("position needs at least two values")
+	}
+	x, xOk := toNumber(array[0])
+	y, yOk := toNumber(array[1])
+	if !xOk || !yOk {
+		return fmt.Errorf("%v is not a numeric position", array)
+	}
+
+	x, y, err := r.convert(x, y)
+	if err != nil {
+		return err
+	}
+	array[0], array[1] = x, y
+	return nil
+}
+
+// convert goes through geographic coordinates when neither side is geographic
+func (r *Reprojector) convert(x, y float64) (float64, float64, error) {
+	if r.from != nil {
+		x, y = r.from.inverse(x, y)
+	} else if math.Abs(y) > 90 {
+		return 0, 0, fmt.Errorf("latitude %v is out of range", y)
+	}
+
+	if r.to != nil {
+		x, y = r.to.forward(x, y)
+	}
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return 0, 0, fmt.Errorf("could not convert %v, %v from %s to %s", x, y, r.spec.From, r.spec.To)
+	}
+	return x, y, nil
+}
@@ -0,0 +1,290 @@
+package ghostdoc
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// meltSpec configures wide to long reshaping
+type meltSpec struct {
+	Columns   []string `json:"columns"`
+	Pattern   string   `json:"pattern"`
+	ID        []string `json:"id"`
+	Channel   string   `json:"channel"`
+	Value     string   `json:"value"`
+	DropEmpty bool     `json:"drop_empty"`
+	pattern   *regexp.Regexp
+}
+
+// pivotSpec configures long to wide reshaping
+type pivotSpec struct {
+	Keys    []string `json:"keys"`
+	Channel string   `json:"channel"`
+	Value   string   `json:"value"`
+	Prefix  string   `json:"prefix"`
+	Carry   []string `json:"carry"`
+	Scope   string   `json:"scope"`
+}
+
+// reshapeSpec is the --reshape configuration, holding either melt or pivot
+type reshapeSpec struct {
+	Melt  *meltSpec  `json:"melt"`
+	Pivot *pivotSpec `json:"pivot"`
+}
+
+// Reshaper melts channel columns (AdcCh0...AdcCh7) into one document per
+// channel, or pivots such long documents back into one document per key.
+type Reshaper struct {
+	context context.GhostContext
+	spec    *reshapeSpec
+	err     error
+}
+
+// NewReshaper factory
+func NewReshaper(c context.GhostContext) *Reshaper {
+	r := &Reshaper{context: c}
+	if spec := c.GlobalString("reshape"); spec != "" {
+		r.spec = &reshapeSpec{}
+		if r.err = util.ReadConfig(spec, r.spec); r.err == nil {
+			r.err = r.validate()
+		}
+		if r.err != nil {
+			r.err = errors.New("[Reshape Error] " + r.err.Error())
+		}
+	}
+	return r
+}
+
+func (r *Reshaper) validate() error {
+	if (r.spec.Melt == nil) == (r.spec.Pivot == nil) {
+		return errors.New("reshape needs either melt or pivot")
+	}
+
+	if melt := r.spec.Melt; melt != nil {
+		if len(melt.Columns) == 0 && melt.Pattern == "" {
+			return errors.New("melt needs columns or a pattern")
+		}
+		if melt.Pattern != "" {
+			var err error
+			if melt.pattern, err = regexp.Compile(melt.Pattern); err != nil {
+				return err
+			}
+		}
+		melt.Channel, melt.Value = defaultKey(melt.Channel, "channel"), defaultKey(melt.Value, "value")
+	}
+
+	if pivot := r.spec.Pivot; pivot != nil {
+		if len(pivot.Keys) == 0 {
+			return errors.New("pivot needs at least one key")
+		}
+		pivot.Channel, pivot.Value = defaultKey(pivot.Channel, "channel"), defaultKey(pivot.Value, "value")
+	}
+	return nil
+}
+
+func defaultKey(key string, fallback string) string {
+	if key == "" {
+		return fallback
+	}
+	return key
+}
+
+func (r *Reshaper) stage(in chan *dataFile) chan *dataFile {
+	if r.spec == nil {
+		return in
+	}
+
+	out := make(chan *dataFile, cap(in))
+	go func() {
+		if r.spec.Melt != nil {
+			r.melt(in, out)
+		} else {
+			r.pivot(in, out)
+		}
+		close(out)
+	}()
+	return out
+}
+
+// melt emits one document per channel column holding the id columns (all
+// other columns by default), the channel and the value. Documents without
+// channel columns are logged and passed on unchanged
+func (r *Reshaper) melt(in chan *dataFile, out chan *dataFile) {
+	melt := r.spec.Melt
+	for data := range in {
+		channels, ids := r.meltColumns(data.data)
+		if len(channels) == 0 {
+			log.WithFields(log.Fields{"file": data.name, "row": data.row}).Warn("[Reshape] No channel columns to melt, keeping the document")
+			out <- data
+			continue
+		}
+
+		for _, column := range channels {
+			value := data.data[column.name]
+			if melt.DropEmpty && (value == nil || value == "") {
+				continue
+			}
+
+			doc := make(map[string]interface{})
+			for _, id := range ids {
+				if idValue, ok := util.GetPath(data.data, id); ok {
+					util.SetPath(doc, id, idValue)
+				}
+			}
+			util.SetPath(doc, melt.Channel, column.channel)
+			util.SetPath(doc, melt.Value, value)
+			out <- &dataFile{name: data.name, data: doc, source: data.source, row: data.row}
+		}
+	}
+}
+
+// meltColumn is a channel column and the channel it holds
+type meltColumn struct {
+	name    string
+	channel interface{}
+}
+
+// meltColumns returns the channel columns in the order of the listed
+// columns, else in natural key order (AdcCh2 before AdcCh10), and the id columns. A pattern capture group is
+// used as the channel, numbers become numeric channels.
+func (r *Reshaper) meltColumns(data map[string]interface{}) ([]meltColumn, []string) {
+	melt := r.spec.Melt
+	var channels []meltColumn
+	melted := make(map[string]bool)
+
+	names := melt.Columns
+	if len(names) == 0 {
+		names = sortedKeys(data)
+	}
+
+	for _, name := range names {
+		if _, ok := data[name]; !ok {
+			continue
+		}
+		column := meltColumn{name: name, channel: name}
+		if melt.pattern != nil {
+			match := melt.pattern.FindStringSubmatch(name)
+			if match == nil {
+				continue
+			}
+			if len(match) > 1 {
+				column.channel = match[1]
+				if number, ok := toNumber(match[1]); ok {
+					column.channel = number
+				}
+			}
+		}
+		channels = append(channels, column)
+		melted[name] = true
+	}
+
+	ids := melt.ID
+	if len(ids) == 0 {
+		for _, key := range sortedKeys(data) {
+			if !melted[key] {
+				ids = append(ids, key)
+			}
+		}
+	}
+	return channels, ids
+}
+
+// sortedKeys returns the keys in natural order
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return naturalLess(keys[i], keys[j]) })
+	return keys
+}
+
+// naturalLess compares strings with runs of digits compared by their number
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := isDigit(a[0]), isDigit(b[0])
+		if aDigits != bDigits {
+			return a < b
+		}
+
+		aRun, bRun := leadingRun(a, aDigits), leadingRun(b, bDigits)
+		if aDigits {
+			aNumber, bNumber := strings.TrimLeft(aRun, "0"), strings.TrimLeft(bRun, "0")
+			if len(aNumber) != len(bNumber) {
+				return len(aNumber) < len(bNumber)
+			}
+		}
+		if aRun != bRun {
+			return aRun < bRun
+		}
+		a, b = a[len(aRun):], b[len(bRun):]
+	}
+	return a < b
+}
+
+// leadingRun returns the leading digits, or the leading non digits
+func leadingRun(s string, digits bool) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// pivot collects the documents by key and emits one document per key with a
+// column per channel. Documents are emitted at the end of every input file,
+// or at the end of the run when scope is "run".
+func (r *Reshaper) pivot(in chan *dataFile, out chan *dataFile) {
+	pivot := r.spec.Pivot
+	var order []string
+	var input interface{}
+	docs := make(map[string]*dataFile)
+
+	flush := func() {
+		for _, key := range order {
+			out <- docs[key]
+		}
+		order, docs = nil, make(map[string]*dataFile)
+	}
+
+	for data := range in {
+		if pivot.Scope != "run" && data.input() != input {
+			flush()
+		}
+		input = data.input()
+
+		key, values := keyValues(data.data, pivot.Keys)
+		doc, ok := docs[key]
+		if !ok {
+			doc = &dataFile{name: data.name, data: make(map[string]interface{}), source: data.source}
+			for i, key := range pivot.Keys {
+				util.SetPath(doc.data, key, values[i])
+			}
+			for _, carry := range pivot.Carry {
+				if value, ok := util.GetPath(data.data, carry); ok {
+					util.SetPath(doc.data, carry, value)
+				}
+			}
+			docs[key] = doc
+			order = append(order, key)
+		}
+
+		channel, ok := util.GetPath(data.data, pivot.Channel)
+		if !ok || channel == nil {
+			continue
+		}
+		value, _ := util.GetPath(data.data, pivot.Value)
+		doc.data[pivot.Prefix+toText(channel)] = value
+	}
+	flush()
+}
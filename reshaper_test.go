@@ -0,0 +1,122 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+// runReshape runs the reshape stage over docs of the same file
+func runReshape(t *testing.T, spec string, docs []map[string]interface{}) []map[string]interface{} {
+	t.Helper()
+	r := NewReshaper(newTestContext(map[string]interface{}{"reshape": spec}))
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	in := make(chan *dataFile, len(docs))
+	for _, doc := range docs {
+		in <- &dataFile{name: "logger.csv", data: doc}
+	}
+	close(in)
+
+	var got []map[string]interface{}
+	for data := range r.stage(in) {
+		got = append(got, data.data)
+	}
+	return got
+}
+
+func TestReshape(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		in   []map[string]interface{}
+		want []map[string]interface{}
+	}{
+		{
+			name: "melt pattern in natural order",
+			spec: `{"melt": {"pattern": "^AdcCh(\\d+)$", "id": ["time"]}}`,
+			in:   []map[string]interface{}{{"time": "t1", "AdcCh10": 10.0, "AdcCh2": 2.0, "AdcCh1": 1.0}},
+			want: []map[string]interface{}{
+				{"time": "t1", "channel": 1.0, "value": 1.0},
+				{"time": "t1", "channel": 2.0, "value": 2.0},
+				{"time": "t1", "channel": 10.0, "value": 10.0},
+			},
+		},
+		{
+			name: "melt columns with default ids and drop empty",
+			spec: `{"melt": {"columns": ["b", "a"], "channel": "ch", "value": "v", "drop_empty": true}}`,
+			in:   []map[string]interface{}{{"id": 1.0, "a": "", "b": 3.0}},
+			want: []map[string]interface{}{{"id": 1.0, "ch": "b", "v": 3.0}},
+		},
+		{
+			name: "melt keeps documents without channel columns",
+			spec: `{"melt": {"pattern": "^AdcCh(\\d+)$"}}`,
+			in:   []map[string]interface{}{{"time": "t1", "note": "restart"}},
+			want: []map[string]interface{}{{"time": "t1", "note": "restart"}},
+		},
+		{
+			name: "pivot",
+			spec: `{"pivot": {"keys": ["time"], "prefix": "AdcCh", "carry": ["site"]}}`,
+			in: []map[string]interface{}{
+				{"time": "t1", "site": "A", "channel": 1.0, "value": 0.5},
+				{"time": "t1", "site": "B", "channel": 2.0, "value": 0.7},
+				{"time": "t2", "site": "A", "channel": 1.0, "value": 0.6},
+			},
+			want: []map[string]interface{}{
+				{"time": "t1", "site": "A", "AdcCh1": 0.5, "AdcCh2": 0.7},
+				{"time": "t2", "site": "A", "AdcCh1": 0.6},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := runReshape(t, test.spec, test.in); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReshapeSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "melt and pivot", spec: `{"melt": {"columns": ["a"]}, "pivot": {"keys": ["id"]}}`},
+		{name: "neither", spec: `{}`},
+		{name: "melt without columns", spec: `{"melt": {}}`},
+		{name: "bad pattern", spec: `{"melt": {"pattern": "("}}`},
+		{name: "pivot without keys", spec: `{"pivot": {}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if r := NewReshaper(newTestContext(map[string]interface{}{"reshape": test.spec})); r.err == nil {
+				t.Error("expected a spec error")
+			}
+		})
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"AdcCh2", "AdcCh10", true},
+		{"AdcCh10", "AdcCh2", false},
+		{"AdcCh2", "AdcCh2", false},
+		{"a", "b", true},
+		{"AdcCh", "AdcCh0", true},
+		{"ch02", "ch3", true},
+		{"1b", "10a", true},
+	}
+
+	for _, test := range tests {
+		if got := naturalLess(test.a, test.b); got != test.want {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// spatialSpec is the --within configuration
+type spatialSpec struct {
+	Lat      string    `json:"lat"`
+	Lon      string    `json:"lon"`
+	Geometry string    `json:"geometry"`
+	Bbox     []float64 `json:"bbox"`
+	Polygon  string    `json:"polygon"`
+	Keep     string    `json:"keep"`
+	Match    string    `json:"match"`
+	Missing  string    `json:"missing"`
+}
+
+// ring is a closed linear ring of [lon, lat] positions. Rings around a pole
+// are unwrapped and closed over the pole so they can be tested in the plane
+type ring struct {
+	points [][2]float64
+	pole   float64
+}
+
+// area is a polygon, the first ring is the exterior and the others are holes
+type area []ring
+
+// SpatialFilter keeps documents inside (or outside) a bounding box or the
+// polygons of a GeoJSON file. Bounding boxes with west > east cross the
+// antimeridian. Polygon edges are straight lon/lat segments, polygons may use
+// longitudes beyond ±180 or encircle a pole.
+type SpatialFilter struct {
+	context context.GhostContext
+	spec    spatialSpec
+	areas   []area
+	err     error
+}
+
+// NewSpatialFilter factory
+func NewSpatialFilter(c context.GhostContext) *SpatialFilter {
+	s := &SpatialFilter{context: c}
+	if spec := c.GlobalString("within"); spec != "" {
+		if s.err = util.ReadConfig(spec, &s.spec); s.err == nil {
+			s.err = s.compile()
+		}
+	}
+	return s
+}
+
+func (s *SpatialFilter) compile() error {
+	if s.spec.Geometry == "" && (s.spec.Lat == "" || s.spec.Lon == "") {
+		return errors.New("within needs lat and lon or a geometry")
+	}
+	if (len(s.spec.Bbox) == 0) == (s.spec.Polygon == "") {
+		return errors.New("within needs either a bbox or a polygon")
+	}
+	if len(s.spec.Bbox) != 0 && (len(s.spec.Bbox) != 4 || s.spec.Bbox[1] > s.spec.Bbox[3]) {
+		return errors.New("bbox is [west, south, east, north]")
+	}
+	if s.spec.Keep != "" && s.spec.Keep != "inside" && s.spec.Keep != "outside" {
+		return fmt.Errorf("keep is inside or outside, not %q", s.spec.Keep)
+	}
+	if s.spec.Missing != "" && s.spec.Missing != "keep" && s.spec.Missing != "drop" && s.spec.Missing != "error" {
+		return fmt.Errorf("missing is keep, drop or error, not %q", s.spec.Missing)
+	}
+
+	if s.spec.Polygon != "" {
+		var geojson map[string]interface{}
+		if err := util.ReadConfig(s.spec.Polygon, &geojson); err != nil {
+			return fmt.Errorf("%s: %v", s.spec.Polygon, err)
+		}
+		if err := s.loadAreas(geojson); err != nil {
+			return fmt.Errorf("%s: %v", s.spec.Polygon, err)
+		}
+		if len(s.areas) == 0 {
+			return fmt.Errorf("%s: no polygons found", s.spec.Polygon)
+		}
+	}
+	return nil
+}
+
+// loadAreas collects the polygons of a FeatureCollection, Feature or geometry
+func (s *SpatialFilter) loadAreas(geojson map[string]interface{}) error {
+	switch geojson["type"] {
+	case "FeatureCollection":
+		features, _ := geojson["features"].([]interface{})
+		for _, feature := range features {
+			if object, ok := feature.(map[string]interface{}); ok {
+				if err := s.loadAreas(object); err != nil {
+					return err
+				}
+			}
+		}
+	case "Feature":
+		if geometry, ok := geojson["geometry"].(map[string]interface{}); ok {
+			return s.loadAreas(geometry)
+		}
+	case "GeometryCollection":
+		geometries, _ := geojson["geometries"].([]interface{})
+		for _, geometry := range geometries {
+			if object, ok := geometry.(map[string]interface{}); ok {
+				if err := s.loadAreas(object); err != nil {
+					return err
+				}
+			}
+		}
+	case "Polygon":
+		polygon, err := s.area(geojson["coordinates"])
+		if err != nil {
+			return err
+		}
+		s.areas = append(s.areas, polygon)
+	case "MultiPolygon":
+		polygons, _ := geojson["coordinates"].([]interface{})
+		for _, coordinates := range polygons {
+			polygon, err := s.area(coordinates)
+			if err != nil {
+				return err
+			}
+			s.areas = append(s.areas, polygon)
+		}
+	}
+	return nil
+}
+
+func (s *SpatialFilter) area(coordinates interface{}) (area, error) {
+	rings, _ := coordinates.([]interface{})
+	if len(rings) == 0 {
+		return nil, errors.New("polygon without rings")
+	}
+
+	var polygon area
+	for _, value := range rings {
+		var points [][2]float64
+		for _, position := range collectPositions(value) {
+			points = append(points, [2]float64{position[0], position[1]})
+		}
+		if len(points) < 3 {
+			return nil, errors.New("polygon ring needs at least three positions")
+		}
+		polygon = append(polygon, newRing(points))
+	}
+	return polygon, nil
+}
+
+// newRing keeps the edges as straight lon/lat segments (RFC 7946), so rings
+// crossing the antimeridian use longitudes beyond ±180 or are split at it.
+// Only a ring that winds once around a pole, taking the short way between
+// positions, is unwrapped and closed over that pole
+func newRing(points [][2]float64) ring {
+	winding := 0.0
+	for i := range points {
+		winding += wrapDelta(points[(i+1)%len(points)][0] - points[i][0])
+	}
+	if math.Abs(winding) < 180 {
+		return ring{points: points}
+	}
+
+	latSum := points[0][1]
+	unwrapped := [][2]float64{points[0]}
+	for i := 1; i < len(points); i++ {
+		previous := unwrapped[i-1]
+		unwrapped = append(unwrapped, [2]float64{previous[0] + wrapDelta(points[i][0]-points[i-1][0]), points[i][1]})
+		latSum += points[i][1]
+	}
+
+	// Close the ring over the pole on the side of the ring
+	last := unwrapped[len(unwrapped)-1]
+	end := last[0] + wrapDelta(points[0][0]-last[0])
+	pole := 90.0
+	if latSum < 0 {
+		pole = -90
+	}
+	unwrapped = append(unwrapped, [2]float64{end, points[0][1]}, [2]float64{end, pole}, [2]float64{points[0][0], pole})
+	return ring{points: unwrapped, pole: pole}
+}
+
+// wrapDelta wraps a longitude difference into [-180, 180)
+func wrapDelta(delta float64) float64 {
+	return math.Mod(math.Mod(delta+180, 360)+360, 360) - 180
+}
+
+// contains tests the point and its copies shifted by a full turn, since
+// unwrapped rings and rings beyond ±180 span more than [-180, 180]. A pole is
+// tested just below it, where every longitude lies on the same side of the ring
+func (r ring) contains(lon, lat float64) bool {
+	if math.Abs(lat) >= 90 {
+		if r.pole != 0 {
+			return r.pole == math.Copysign(90, lat)
+		}
+		lat = math.Copysign(90-1e-9, lat)
+	}
+	for _, shift := range []float64{0, 360, -360} {
+		if pointInRing(r.points, lon+shift, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// pointInRing is the even-odd ray casting test
+func pointInRing(points [][2]float64, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		xi, yi, xj, yj := points[i][0], points[i][1], points[j][0], points[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (a area) contains(lon, lat float64) bool {
+	if !a[0].contains(lon, lat) {
+		return false
+	}
+	for _, hole := range a[1:] {
+		if hole.contains(lon, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter returns nil for documents that should be dropped
+func (s *SpatialFilter) filter(data map[string]interface{}) (map[string]interface{}, error) {
+	if s.err != nil {
+		return data, errors.New("within: " + s.err.Error())
+	}
+	if len(s.spec.Bbox) == 0 && s.spec.Polygon == "" {
+		return data, nil
+	}
+
+	positions, err := s.positions(data)
+	if err != nil {
+		return data, errors.New("within: " + err.Error())
+	}
+	if len(positions) == 0 {
+		switch s.spec.Missing {
+		case "drop":
+			return nil, nil
+		case "error":
+			return data, errors.New("within: document has no location")
+		}
+		return data, nil
+	}
+
+	inside := s.spec.Match != "any"
+	for _, position := range positions {
+		if s.contains(position[0], position[1]) == (s.spec.Match == "any") {
+			inside = !inside
+			break
+		}
+	}
+
+	if inside == (s.spec.Keep == "outside") {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// positions returns the lon/lat of the document or all positions of its geometry
+func (s *SpatialFilter) positions(data map[string]interface{}) ([][]float64, error) {
+	if s.spec.Geometry != "" {
+		geometry, ok := util.GetPath(data, s.spec.Geometry)
+		if !ok || geometry == nil {
+			return nil, nil
+		}
+		if _, ok := geometry.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%s is not a GeoJSON geometry", s.spec.Geometry)
+		}
+		return collectPositions(geometry), nil
+	}
+
+	lat, latOk := util.GetPath(data, s.spec.Lat)
+	lon, lonOk := util.GetPath(data, s.spec.Lon)
+	if !latOk || !lonOk || lat == nil || lon == nil || lat == "" || lon == "" {
+		return nil, nil
+	}
+
+	latValue, err := parseCoordinate(lat, "NS", 90)
+	if err != nil {
+		return nil, fmt.Errorf("lat: %v", err)
+	}
+	lonValue, err := parseCoordinate(lon, "EW", 180)
+	if err != nil {
+		return nil, fmt.Errorf("lon: %v", err)
+	}
+	return [][]float64{{lonValue, latValue}}, nil
+}
+
+func (s *SpatialFilter) contains(lon, lat float64) bool {
+	if len(s.spec.Bbox) == 4 {
+		west, south, east, north := s.spec.Bbox[0], s.spec.Bbox[1], s.spec.Bbox[2], s.spec.Bbox[3]
+		if lat < south || lat > north {
+			return false
+		}
+		if math.Abs(lat) >= 90 {
+			return true
+		}
+		if west <= east {
+			return lon >= west && lon <= east
+		}
+		return lon >= west || lon <= east
+	}
+
+	for _, polygon := range s.areas {
+		if polygon.contains(lon, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectPositions walks nested GeoJSON coordinate arrays (and geometry
+// objects) down to the numeric [lon, lat] positions
+func collectPositions(value interface{}) [][]float64 {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if geometries, ok := typed["geometries"].([]interface{}); ok {
+			return collectPositions(geometries)
+		}
+		return collectPositions(typed["coordinates"])
+	case []interface{}:
+		if len(typed) >= 2 {
+			lon, lonOk := toNumber(typed[0])
+			lat, latOk := toNumber(typed[1])
+			if _, nested := typed[0].([]interface{}); !nested && lonOk && latOk {
+				return [][]float64{{lon, lat}}
+			}
+		}
+		var positions [][]float64
+		for _, child := range typed {
+			positions = append(positions, collectPositions(child)...)
+		}
+		return positions
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package ghostdoc
+
+import (
+	"testing"
+)
+
+func TestRingContains(t *testing.T) {
+	tests := []struct {
+		name   string
+		points [][2]float64
+		lon    float64
+		lat    float64
+		want   bool
+	}{
+		{"box", [][2]float64{{10, 50}, {20, 50}, {20, 60}, {10, 60}}, 15, 55, true},
+		{"outside box", [][2]float64{{10, 50}, {20, 50}, {20, 60}, {10, 60}}, 25, 55, false},
+		{"straight edges", [][2]float64{{170, 50}, {-170, 50}, {-170, 60}, {170, 60}, {170, 50}}, 0, 55, true},
+		{"straight edges do not cross the antimeridian", [][2]float64{{170, 50}, {-170, 50}, {-170, 60}, {170, 60}, {170, 50}}, 175, 55, false},
+		{"wider than 180", [][2]float64{{-100, 60}, {100, 60}, {100, 85}, {-100, 85}}, 0, 70, true},
+		{"wider than 180 complement", [][2]float64{{-100, 60}, {100, 60}, {100, 85}, {-100, 85}}, 180, 70, false},
+		{"beyond 180 east side", [][2]float64{{170, 50}, {190, 50}, {190, 60}, {170, 60}}, 175, 55, true},
+		{"beyond 180 west side", [][2]float64{{170, 50}, {190, 50}, {190, 60}, {170, 60}}, -175, 55, true},
+		{"beyond 180 complement", [][2]float64{{170, 50}, {190, 50}, {190, 60}, {170, 60}}, 0, 55, false},
+		{"arctic cap", [][2]float64{{-180, 66.5}, {-90, 66.5}, {0, 66.5}, {90, 66.5}, {180, 66.5}}, 45, 80, true},
+		{"arctic cap pole", [][2]float64{{-180, 66.5}, {-90, 66.5}, {0, 66.5}, {90, 66.5}, {180, 66.5}}, 0, 90, true},
+		{"arctic cap south of ring", [][2]float64{{-180, 66.5}, {-90, 66.5}, {0, 66.5}, {90, 66.5}, {180, 66.5}}, 45, 60, false},
+		{"antarctic cap", [][2]float64{{0, -60}, {120, -60}, {-120, -60}, {0, -60}}, 100, -70, true},
+		{"antarctic cap north of ring", [][2]float64{{0, -60}, {120, -60}, {-120, -60}, {0, -60}}, 100, -50, false},
+	}
+
+	for _, test := range tests {
+		if got := newRing(test.points).contains(test.lon, test.lat); got != test.want {
+			t.Errorf("%s: contains(%v, %v) = %v, want %v", test.name, test.lon, test.lat, got, test.want)
+		}
+	}
+}
+
+func TestSpatialFilter(t *testing.T) {
+	polygon := writeTestFile(t, "area.geojson", `{"type": "MultiPolygon", "coordinates": [[[[170, 50], [180, 50], [180, 60], [170, 60], [170, 50]]], [[[-180, 50], [-170, 50], [-170, 60], [-180, 60], [-180, 50]]]]}`)
+	tests := []struct {
+		name string
+		spec string
+		in   map[string]interface{}
+		keep bool
+		err  bool
+	}{
+		{"bbox inside", `{"lat": "lat", "lon": "lon", "bbox": [-30, 66.5, 60, 90]}`, map[string]interface{}{"lat": 78.2, "lon": 15.6}, true, false},
+		{"bbox outside", `{"lat": "lat", "lon": "lon", "bbox": [-30, 66.5, 60, 90]}`, map[string]interface{}{"lat": 60.0, "lon": 15.6}, false, false},
+		{"bbox keep outside", `{"lat": "lat", "lon": "lon", "bbox": [-30, 66.5, 60, 90], "keep": "outside"}`, map[string]interface{}{"lat": 60.0, "lon": 15.6}, true, false},
+		{"bbox across the antimeridian", `{"lat": "lat", "lon": "lon", "bbox": [170, 50, -170, 60]}`, map[string]interface{}{"lat": 55.0, "lon": -175.0}, true, false},
+		{"polygon split at the antimeridian", `{"lat": "lat", "lon": "lon", "polygon": "` + polygon + `"}`, map[string]interface{}{"lat": 55.0, "lon": 175.0}, true, false},
+		{"polygon split at the antimeridian west side", `{"lat": "lat", "lon": "lon", "polygon": "` + polygon + `"}`, map[string]interface{}{"lat": 55.0, "lon": -175.0}, true, false},
+		{"polygon complement", `{"lat": "lat", "lon": "lon", "polygon": "` + polygon + `"}`, map[string]interface{}{"lat": 55.0, "lon": 0.0}, false, false},
+		{"missing location is kept", `{"lat": "lat", "lon": "lon", "bbox": [-30, 66.5, 60, 90]}`, map[string]interface{}{"name": "x"}, true, false},
+		{"missing location dropped", `{"lat": "lat", "lon": "lon", "bbox": [-30, 66.5, 60, 90], "missing": "drop"}`, map[string]interface{}{"name": "x"}, false, false},
+		{"missing location error", `{"lat": "lat", "lon": "lon", "bbox": [-30, 66.5, 60, 90], "missing": "error"}`, map[string]interface{}{"name": "x"}, true, true},
+		{"missing geometry is kept", `{"geometry": "geometry", "bbox": [-30, 66.5, 60, 90]}`, map[string]interface{}{"name": "x"}, true, false},
+		{"match all", `{"geometry": "geometry", "bbox": [-30, 66.5, 60, 90]}`, map[string]interface{}{"geometry": map[string]interface{}{"type": "LineString", "coordinates": []interface{}{[]interface{}{15.0, 78.0}, []interface{}{15.0, 60.0}}}}, false, false},
+		{"match any", `{"geometry": "geometry", "bbox": [-30, 66.5, 60, 90], "match": "any"}`, map[string]interface{}{"geometry": map[string]interface{}{"type": "LineString", "coordinates": []interface{}{[]interface{}{15.0, 78.0}, []interface{}{15.0, 60.0}}}}, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewSpatialFilter(newTestContext(map[string]interface{}{"within": test.spec}))
+			if s.err != nil {
+				t.Fatal(s.err)
+			}
+			got, err := s.filter(test.in)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if (got != nil) != test.keep {
+				t.Errorf("kept %v, want %v", got != nil, test.keep)
+			}
+		})
+	}
+}
+
+func TestSpatialFilterSpec(t *testing.T) {
+	tests := []string{
+		`{"bbox": [0, 0, 10, 10]}`,
+		`{"lat": "lat", "lon": "lon"}`,
+		`{"lat": "lat", "lon": "lon", "bbox": [0, 10, 10, 0]}`,
+		`{"lat": "lat", "lon": "lon", "bbox": [0, 0, 10, 10], "keep": "nope"}`,
+		`{"lat": "lat", "lon": "lon", "bbox": [0, 0, 10, 10], "missing": "nope"}`,
+	}
+
+	for _, spec := range tests {
+		if s := NewSpatialFilter(newTestContext(map[string]interface{}{"within": spec})); s.err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
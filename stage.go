@@ -0,0 +1,24 @@
+package ghostdoc
+
+// stage processes the documents sequentially in input order before they
+// reach the concurrent mappers. Stages can hold state across documents and
+// emit more or fewer documents than they receive. Documents from one input
+// file are always consecutive, so a change of input marks the end of a file.
+type stage func(in chan *dataFile) chan *dataFile
+
+// input identifies the input file of the document. Names of remote input are
+// not unique, so parsed documents are told apart by their raw file
+func (d *dataFile) input() interface{} {
+	if d.source != nil {
+		return d.source
+	}
+	return d.name
+}
+
+// runStages chains the stages and returns the output of the last one
+func runStages(in chan *dataFile, stages []stage) chan *dataFile {
+	for _, s := range stages {
+		in = s(in)
+	}
+	return in
+}
@@ -0,0 +1,28 @@
+package ghostdoc
+
+import (
+	"sync/atomic"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// runSummary counts what happened to the documents passing through a Writer
+type runSummary struct {
+	documents int64
+	published int64
+	dropped   int64
+	failed    int64
+}
+
+func (s *runSummary) count(counter *int64) {
+	atomic.AddInt64(counter, 1)
+}
+
+func (s *runSummary) log() {
+	log.WithFields(log.Fields{
+		"documents": atomic.LoadInt64(&s.documents),
+		"published": atomic.LoadInt64(&s.published),
+		"dropped":   atomic.LoadInt64(&s.dropped),
+		"failed":    atomic.LoadInt64(&s.failed),
+	}).Info("Summary")
+}
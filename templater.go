@@ -0,0 +1,134 @@
+package ghostdoc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+// templateTimeLayouts are tried in order when a template formats a date
+var templateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// Templater sets fields from Go text/template expressions evaluated over
+// the document, e.g. {"title": "{{.station}} {{.date | year}}"}. All
+// templates see the document as it was before any of them ran.
+type Templater struct {
+	context   context.GhostContext
+	keys      []string
+	templates map[string]*template.Template
+	err       error
+}
+
+// NewTemplater factory
+func NewTemplater(c context.GhostContext) *Templater {
+	t := &Templater{context: c}
+	if spec := c.GlobalString("template"); spec != "" {
+		var fields map[string]string
+		if t.err = util.ReadConfig(spec, &fields); t.err == nil {
+			t.err = t.compile(fields)
+		}
+	}
+	return t
+}
+
+func (t *Templater) compile(fields map[string]string) error {
+	t.templates = make(map[string]*template.Template)
+	for key, text := range fields {
+		tmpl, err := template.New(key).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return err
+		}
+		t.templates[key] = tmpl
+		t.keys = append(t.keys, key)
+	}
+	sort.Strings(t.keys)
+	return nil
+}
+
+func (t *Templater) apply(data map[string]interface{}) (map[string]interface{}, error) {
+	if t.err != nil {
+		return data, errors.New("template: " + t.err.Error())
+	}
+
+	results := make(map[string]string)
+	for _, key := range t.keys {
+		var buf bytes.Buffer
+		// Execution errors already start with "template:"
+		if err := t.templates[key].Execute(&buf, data); err != nil {
+			return data, err
+		}
+		results[key] = buf.String()
+	}
+
+	for _, key := range t.keys {
+		if err := util.SetPath(data, key, results[key]); err != nil {
+			return data, errors.New("template: " + err.Error())
+		}
+	}
+	return data, nil
+}
+
+// templateFuncs are available in addition to the text/template builtins (printf, index, ...)
+var templateFuncs = template.FuncMap{
+	"get": func(data map[string]interface{}, path string) interface{} {
+		if value, ok := util.GetPath(data, path); ok && value != nil {
+			return value
+		}
+		return ""
+	},
+	"lower": func(value interface{}) string { return strings.ToLower(toText(value)) },
+	"upper": func(value interface{}) string { return strings.ToUpper(toText(value)) },
+	"trim":  func(value interface{}) string { return strings.TrimSpace(toText(value)) },
+	"date":  templateDate,
+	"year": func(value interface{}) (int, error) {
+		parsed, err := templateTime(value)
+		return parsed.Year(), err
+	},
+	"sha1": func(values ...interface{}) string {
+		var parts []string
+		for _, value := range values {
+			parts = append(parts, toText(value))
+		}
+		sum := sha1.Sum([]byte(strings.Join(parts, "")))
+		return hex.EncodeToString(sum[:])
+	},
+	"join": func(sep string, value interface{}) string {
+		list, ok := value.([]interface{})
+		if !ok {
+			return toText(value)
+		}
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = toText(item)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// templateDate formats a date value with a Go layout: {{.date | date "2006-01-02"}}
+func templateDate(layout string, value interface{}) (string, error) {
+	parsed, err := templateTime(value)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format(layout), nil
+}
+
+func templateTime(value interface{}) (time.Time, error) {
+	text := toText(value)
+	for _, layout := range templateTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("could not read %q as a date", text)
+}
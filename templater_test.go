@@ -0,0 +1,33 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplater(t *testing.T) {
+	tp := NewTemplater(newTestContext(map[string]interface{}{"template": `{
+		"title": "{{.station | upper}} {{.date | year}}",
+		"day": "{{.date | date \"02.01.2006\"}}",
+		"meta.name": "{{get . \"pos.name\" | trim | lower}}",
+		"station": "{{.title}}"}`}))
+	if tp.err != nil {
+		t.Fatal(tp.err)
+	}
+
+	got, err := tp.apply(map[string]interface{}{"station": "troll", "title": "x", "date": "2016-03-01 12:00:00", "pos": map[string]interface{}{"name": " Zeppelin "}})
+	want := map[string]interface{}{
+		"station": "x", "title": "TROLL 2016", "day": "01.03.2016", "date": "2016-03-01 12:00:00",
+		"pos": map[string]interface{}{"name": " Zeppelin "}, "meta": map[string]interface{}{"name": "zeppelin"},
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+
+	if _, err := tp.apply(map[string]interface{}{"station": "troll", "date": "yesterday"}); err == nil {
+		t.Error("expected an error for a missing key and an unknown date")
+	}
+	if tp := NewTemplater(newTestContext(map[string]interface{}{"template": `{"title": "{{.station"}`})); tp.err == nil {
+		t.Error("expected a template parse error")
+	}
+}
@@ -20,13 +20,28 @@ func TextCommand() cli.Command {
 				Name:  "pattern, p",
 				Usage: "use a pattern file to specify which text segments should be extracted",
 			},
+			cli.BoolFlag{
+				Name:  "decimal-comma, dc",
+				Usage: "Numbers use a decimal comma (12,5)",
+			},
+			cli.StringFlag{
+				Name:  "thousands, ts",
+				Usage: "Thousands separator chars in numbers, e.g. \" \" or \".\" (1 234,5)",
+			},
+			cli.BoolFlag{
+				Name:  "unicode-minus, um",
+				Usage: "Read the unicode minus sign (−) as a minus",
+			},
 		},
 		Action: processText,
 	}
 }
 
 func processText(c *cli.Context) {
-	textStrategy := NewTextStrategy(context.NewCliContext(c))
+	textStrategy, err := NewTextStrategy(context.NewCliContext(c))
+	if err != nil {
+		panic(err.Error())
+	}
 	parser := NewParser(textStrategy)
 	parser.process()
 }
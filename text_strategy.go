@@ -1,25 +1,34 @@
 package ghostdoc
 
 import (
+	"io/ioutil"
 	"regexp"
+	"strconv"
 	"strings"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/npolar/ghostdoc/context"
 )
 
 // TextStrategy typedef
 type TextStrategy struct {
 	context context.GhostContext
+	locale  *numberLocale
 }
 
 const (
-	textFileRegex = `(?i)^.+\.txt$`
-	newlineRegex  = `\n`
+	textFileRegex  = `(?i)^.+\.txt$`
+	textMediaRegex = `(?i)^text/plain$`
+	newlineRegex   = `\n`
 )
 
 // NewTextStrategy factory
-func NewTextStrategy(context context.GhostContext) *TextStrategy {
-	return &TextStrategy{context: context}
+func NewTextStrategy(context context.GhostContext) (*TextStrategy, error) {
+	locale, err := newNumberLocale(context)
+	if err != nil {
+		return nil, err
+	}
+	return &TextStrategy{context: context, locale: locale}, nil
 }
 
 // rawInput does a lazy check for raw inline input and returns true if matches
@@ -34,19 +43,44 @@ func (t *TextStrategy) isSupportedFile(filename string) bool {
 	return textFile.MatchString(filename)
 }
 
+// isSupportedMediaType returns true if the content type of a remote input is supported
+func (t *TextStrategy) isSupportedMediaType(mediaType string) bool {
+	textMedia := regexp.MustCompile(textMediaRegex)
+	return textMedia.MatchString(mediaType)
+}
+
 func (t *TextStrategy) getContext() context.GhostContext {
 	return t.context
 }
 
 func (t *TextStrategy) parse(rawFile *rawFile, dataChan chan *dataFile) {
 	var dataMap = make(map[string]interface{})
-	text := t.replaceNewLines(rawFile.data, " ")
-	dataMap[t.context.String("key")] = strings.TrimSpace(string(text))
+	data, err := ioutil.ReadAll(rawFile.reader)
+	if err != nil {
+		log.Error("[Text] Reading error!", err)
+		return
+	}
+
+	text := strings.TrimSpace(string(t.replaceNewLines(data, " ")))
+	dataMap[t.context.String("key")] = t.value(text)
 
 	dataChan <- &dataFile{
-		name: rawFile.name,
-		data: dataMap,
+		name:   rawFile.name,
+		data:   dataMap,
+		source: rawFile,
+	}
+}
+
+// value returns text holding a single locale number as a number
+func (t *TextStrategy) value(text string) interface{} {
+	if t.locale != nil {
+		if plain, ok := t.locale.normalize(text); ok {
+			if number, err := strconv.ParseFloat(plain, 64); err == nil {
+				return number
+			}
+		}
 	}
+	return text
 }
 
 func (t *TextStrategy) replaceNewLines(data []byte, replacement string) []byte {
@@ -0,0 +1,33 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextStrategyParse(t *testing.T) {
+	tests := []struct {
+		values map[string]interface{}
+		in     string
+		want   interface{}
+	}{
+		{map[string]interface{}{"key": "value"}, "  first line\nsecond line\n", "first line second line"},
+		{map[string]interface{}{"key": "value", "decimal-comma": true, "thousands": " "}, "1 234,5\n", 1234.5},
+		{map[string]interface{}{"key": "value", "decimal-comma": true}, "12,5 m", "12,5 m"},
+	}
+
+	for _, test := range tests {
+		s, err := NewTextStrategy(newTestContext(test.values))
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs := parseInput(s, "note.txt", test.in)
+		if len(docs) != 1 || !reflect.DeepEqual(docs[0].data["value"], test.want) {
+			t.Errorf("%q: got %v, want %v", test.in, docs, test.want)
+		}
+	}
+
+	if _, err := NewTextStrategy(newTestContext(map[string]interface{}{"thousands": "."})); err == nil {
+		t.Error("expected a locale error")
+	}
+}
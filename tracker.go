@@ -0,0 +1,175 @@
+package ghostdoc
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+)
+
+const (
+	earthRadius = 6371008.8 // mean earth radius in meters
+)
+
+// trackSpec is the --track configuration
+type trackSpec struct {
+	Time        string   `json:"time"`
+	TimeFormat  string   `json:"time_format"`
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	Group       []string `json:"group"`
+	Prefix      string   `json:"prefix"`
+	MaxSpeed    float64  `json:"max_speed"`
+	MaxDistance float64  `json:"max_distance"`
+}
+
+// trackPoint is the previous fix of a track
+type trackPoint struct {
+	time     time.Time
+	lat, lon float64
+}
+
+// Tracker derives time delta (s), great-circle distance (m), speed (m/s) and
+// bearing (degrees) between consecutive fixes of a track and flags jumps above
+// the configured thresholds. Tracks are per input file, optionally split on
+// group keys for files holding several tracks.
+type Tracker struct {
+	context context.GhostContext
+	spec    *trackSpec
+	dates   *DateNormalizer
+	err     error
+}
+
+// NewTracker factory
+func NewTracker(c context.GhostContext) *Tracker {
+	t := &Tracker{context: c}
+	if spec := c.GlobalString("track"); spec != "" {
+		t.spec = &trackSpec{}
+		if t.err = util.ReadConfig(spec, t.spec); t.err == nil {
+			t.err = t.compile()
+		}
+		if t.err != nil {
+			t.err = errors.New("[Track Error] " + t.err.Error())
+		}
+	}
+	return t
+}
+
+// compile sets up the time parsing with the date normalizer
+func (t *Tracker) compile() error {
+	if t.spec.Time == "" || t.spec.Lat == "" || t.spec.Lon == "" {
+		return errors.New("track needs time, lat and lon keys")
+	}
+	if t.spec.TimeFormat == "" {
+		t.spec.TimeFormat = time.RFC3339
+	}
+
+	t.dates = &DateNormalizer{
+		context:  t.context,
+		location: time.UTC,
+		spec:     dateSpec{Fields: []*dateField{{Key: t.spec.Time, Format: t.spec.TimeFormat}}},
+	}
+	return t.dates.compile()
+}
+
+func (t *Tracker) stage(in chan *dataFile) chan *dataFile {
+	if t.spec == nil {
+		return in
+	}
+
+	out := make(chan *dataFile, cap(in))
+	go func() {
+		var input interface{}
+		previous := make(map[string]*trackPoint)
+
+		for data := range in {
+			if data.input() != input {
+				input, previous = data.input(), make(map[string]*trackPoint)
+			}
+
+			if point, ok := t.point(data.data); ok {
+				key, _ := keyValues(data.data, t.spec.Group)
+				if last := previous[key]; last != nil {
+					t.derive(data.data, last, point)
+				}
+				previous[key] = point
+			}
+			out <- data
+		}
+		close(out)
+	}()
+	return out
+}
+
+// point reads the fix from the document, documents without a valid fix are passed on as is
+func (t *Tracker) point(data map[string]interface{}) (*trackPoint, bool) {
+	value, ok := util.GetPath(data, t.spec.Time)
+	if !ok || value == nil {
+		return nil, false
+	}
+	fixTime, err := t.dates.parse(t.dates.spec.Fields[0], value, data)
+	if err != nil {
+		return nil, false
+	}
+
+	latValue, _ := util.GetPath(data, t.spec.Lat)
+	lonValue, _ := util.GetPath(data, t.spec.Lon)
+	lat, latOk := toNumber(latValue)
+	lon, lonOk := toNumber(lonValue)
+	if !latOk || !lonOk {
+		return nil, false
+	}
+
+	return &trackPoint{time: fixTime, lat: lat, lon: lon}, true
+}
+
+// derive sets the fields describing the move from the last fix to this one
+func (t *Tracker) derive(data map[string]interface{}, last *trackPoint, point *trackPoint) {
+	delta := point.time.Sub(last.time).Seconds()
+	distance := t.distance(last, point)
+
+	util.SetPath(data, t.spec.Prefix+"time_delta", delta)
+	util.SetPath(data, t.spec.Prefix+"distance", distance)
+	util.SetPath(data, t.spec.Prefix+"bearing", t.bearing(last, point))
+
+	var speed interface{}
+	if delta > 0 {
+		speed = distance / delta
+	}
+	util.SetPath(data, t.spec.Prefix+"speed", speed)
+
+	if t.spec.MaxSpeed > 0 || t.spec.MaxDistance > 0 {
+		jump := t.spec.MaxDistance > 0 && distance > t.spec.MaxDistance
+		if t.spec.MaxSpeed > 0 {
+			// a move without time passing can only be a jump
+			jump = jump || speed == nil && distance > 0 || speed != nil && speed.(float64) > t.spec.MaxSpeed
+		}
+		util.SetPath(data, t.spec.Prefix+"jump", jump)
+	}
+}
+
+// distance returns the great-circle distance in meters (haversine)
+func (t *Tracker) distance(from, to *trackPoint) float64 {
+	lat1, lat2 := t.radians(from.lat), t.radians(to.lat)
+	dLat := lat2 - lat1
+	dLon := t.radians(to.lon - from.lon)
+
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// bearing returns the initial great-circle bearing in degrees [0, 360)
+func (t *Tracker) bearing(from, to *trackPoint) float64 {
+	lat1, lat2 := t.radians(from.lat), t.radians(to.lat)
+	dLon := t.radians(to.lon - from.lon)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+func (t *Tracker) radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
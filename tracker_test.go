@@ -0,0 +1,39 @@
+package ghostdoc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(newTestContext(map[string]interface{}{"track": `{"time": "time", "lat": "lat", "lon": "lon", "group": ["id"], "max_speed": 100}`}))
+	if tr.err != nil {
+		t.Fatal(tr.err)
+	}
+
+	fix := func(name, id, time string, lon float64) *dataFile {
+		return &dataFile{name: name, data: map[string]interface{}{"id": id, "time": time, "lat": 0.0, "lon": lon}}
+	}
+	got := runStage(tr.stage,
+		fix("a", "x", "2016-03-01T00:00:00Z", 0),
+		fix("a", "y", "2016-03-01T00:05:00Z", 5),
+		fix("a", "x", "2016-03-01T00:10:00Z", 0.1),
+		fix("a", "x", "2016-03-01T00:20:00Z", -1),
+		fix("b", "x", "2016-03-01T00:30:00Z", -1),
+	)
+
+	degree := earthRadius * math.Pi / 180
+	if got[0]["distance"] != nil || got[1]["distance"] != nil || got[4]["distance"] != nil {
+		t.Errorf("the first fix of every track should have no derived fields: %v", got)
+	}
+	if d := got[2]["distance"].(float64); math.Abs(d-degree/10) > 1e-6 || got[2]["bearing"] != 90.0 || got[2]["time_delta"] != 600.0 || got[2]["jump"] != false {
+		t.Errorf("got %v, want 0.1 degree east in 600 s", got[2])
+	}
+	if got[3]["bearing"] != 270.0 || got[3]["jump"] != true {
+		t.Errorf("got %v, want a jump west", got[3])
+	}
+
+	if tr := NewTracker(newTestContext(map[string]interface{}{"track": `{"time": "time", "lat": "lat"}`})); tr.err == nil {
+		t.Error("expected a spec error")
+	}
+}
@@ -0,0 +1,243 @@
+package ghostdoc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/npolar/ghostdoc/context"
+	"github.com/npolar/ghostdoc/util"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// transformOp is a single value operation in a transform spec
+type transformOp struct {
+	Op        string      `json:"op"`
+	Type      string      `json:"type"`
+	Pattern   string      `json:"pattern"`
+	With      string      `json:"with"`
+	Separator string      `json:"separator"`
+	Digits    int         `json:"digits"`
+	Factor    *float64    `json:"factor"`
+	Value     interface{} `json:"value"`
+	regexp    *regexp.Regexp
+	offset    float64
+}
+
+// Transformer applies the value operations of the --transform spec. The spec
+// maps key paths to a list of operations that run in order.
+// Example: {"temp": [{"op": "cast", "type": "float"}, {"op": "offset", "value": -273.15}]}
+type Transformer struct {
+	context context.GhostContext
+	keys    []string
+	spec    map[string][]*transformOp
+	err     error
+}
+
+// NewTransformer factory
+func NewTransformer(c context.GhostContext) *Transformer {
+	t := &Transformer{context: c}
+	if spec := c.GlobalString("transform"); spec != "" {
+		if err := util.ReadConfig(spec, &t.spec); err == nil {
+			t.err = t.compile()
+		} else {
+			t.err = err
+		}
+	}
+	return t
+}
+
+// compile validates the operations, prepares the regular expressions and
+// sorts the keys so the fields are always transformed in the same order
+func (t *Transformer) compile() error {
+	for key, ops := range t.spec {
+		t.keys = append(t.keys, key)
+		for _, op := range ops {
+			if err := t.compileOp(op); err != nil {
+				return fmt.Errorf("%s %s: %v", key, op.Op, err)
+			}
+		}
+	}
+	sort.Strings(t.keys)
+	return nil
+}
+
+// compileOp checks that the operation has the settings it needs
+func (t *Transformer) compileOp(op *transformOp) error {
+	var err error
+	switch op.Op {
+	case "replace":
+		op.regexp, err = regexp.Compile(op.Pattern)
+	case "cast":
+		switch op.Type {
+		case "string", "float", "number", "int", "integer", "bool", "boolean":
+		default:
+			err = fmt.Errorf("Unknown type %q", op.Type)
+		}
+	case "scale":
+		if op.Factor == nil {
+			err = errors.New("needs a factor")
+		}
+	case "offset":
+		var ok bool
+		if op.offset, ok = op.Value.(float64); !ok {
+			err = fmt.Errorf("needs a numeric value, got %v", op.Value)
+		}
+	case "trim", "lower", "upper", "title", "split", "join", "round", "default":
+	default:
+		err = errors.New("Unknown operation")
+	}
+	return err
+}
+
+func (t *Transformer) transform(data map[string]interface{}) (map[string]interface{}, error) {
+	if t.err != nil {
+		return data, errors.New("transform: " + t.err.Error())
+	}
+
+	for _, key := range t.keys {
+		value, ok := util.GetPath(data, key)
+		for _, op := range t.spec[key] {
+			var err error
+			if op.Op == "default" {
+				if !ok || value == nil {
+					value, ok = op.Value, true
+				}
+			} else if ok && value != nil {
+				if value, err = t.apply(op, value); err != nil {
+					return data, fmt.Errorf("transform: %s %s: %v", key, op.Op, err)
+				}
+			}
+		}
+
+		if ok {
+			if err := util.SetPath(data, key, value); err != nil {
+				return data, errors.New("transform: " + err.Error())
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// apply runs the operation on the value. Operations other than join run on
+// every element when the value is an array
+func (t *Transformer) apply(op *transformOp, value interface{}) (interface{}, error) {
+	if list, ok := value.([]interface{}); ok && !t.wholeValue(op) {
+		result := make([]interface{}, len(list))
+		for i, item := range list {
+			var err error
+			if result[i], err = t.apply(op, item); err != nil {
+				return value, err
+			}
+		}
+		return result, nil
+	}
+
+	switch op.Op {
+	case "cast":
+		return t.cast(value, op.Type)
+	case "trim":
+		return strings.TrimSpace(t.toString(value)), nil
+	case "lower":
+		return strings.ToLower(t.toString(value)), nil
+	case "upper":
+		return strings.ToUpper(t.toString(value)), nil
+	case "title":
+		return cases.Title(language.Und).String(t.toString(value)), nil
+	case "replace":
+		return op.regexp.ReplaceAllString(t.toString(value), op.With), nil
+	case "split":
+		var list []interface{}
+		for _, item := range strings.Split(t.toString(value), op.Separator) {
+			list = append(list, item)
+		}
+		return list, nil
+	case "join":
+		return t.join(value, op.Separator), nil
+	case "round":
+		number, err := t.toFloat(value)
+		pow := math.Pow(10, float64(op.Digits))
+		return math.Round(number*pow) / pow, err
+	case "scale":
+		number, err := t.toFloat(value)
+		return number * *op.Factor, err
+	case "offset":
+		number, err := t.toFloat(value)
+		return number + op.offset, err
+	}
+	return value, nil
+}
+
+// wholeValue returns true for operations that take an array as a whole
+func (t *Transformer) wholeValue(op *transformOp) bool {
+	return op.Op == "join" || op.Op == "cast" && op.Type == "string"
+}
+
+func (t *Transformer) cast(value interface{}, kind string) (interface{}, error) {
+	switch kind {
+	case "string":
+		if list, ok := value.([]interface{}); ok {
+			return t.join(list, ","), nil
+		}
+		return t.toString(value), nil
+	case "float", "number":
+		return t.toFloat(value)
+	case "int", "integer":
+		number, err := t.toFloat(value)
+		return math.Trunc(number), err
+	case "bool", "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			return v != 0, nil
+		default:
+			return strconv.ParseBool(strings.TrimSpace(t.toString(v)))
+		}
+	}
+	return value, fmt.Errorf("Unknown type %q", kind)
+}
+
+func (t *Transformer) join(value interface{}, separator string) string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return t.toString(value)
+	}
+
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = t.toString(item)
+	}
+	return strings.Join(items, separator)
+}
+
+func (t *Transformer) toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (t *Transformer) toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return strconv.ParseFloat(strings.TrimSpace(t.toString(v)), 64)
+	}
+}
@@ -0,0 +1,77 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		in   map[string]interface{}
+		want map[string]interface{}
+	}{
+		{"cast float", `{"a": [{"op": "cast", "type": "float"}]}`, map[string]interface{}{"a": " 12.5 "}, map[string]interface{}{"a": 12.5}},
+		{"cast int", `{"a": [{"op": "cast", "type": "int"}]}`, map[string]interface{}{"a": "12.9"}, map[string]interface{}{"a": 12.0}},
+		{"cast bool", `{"a": [{"op": "cast", "type": "bool"}]}`, map[string]interface{}{"a": "true"}, map[string]interface{}{"a": true}},
+		{"cast string joins arrays", `{"a": [{"op": "cast", "type": "string"}]}`, map[string]interface{}{"a": []interface{}{1.0, "b"}}, map[string]interface{}{"a": "1,b"}},
+		{"trim lower upper", `{"a": [{"op": "trim"}, {"op": "upper"}], "b": [{"op": "lower"}]}`, map[string]interface{}{"a": " x ", "b": "Y"}, map[string]interface{}{"a": "X", "b": "y"}},
+		{"title", `{"a": [{"op": "title"}]}`, map[string]interface{}{"a": "NY-ÅLESUND research STATION"}, map[string]interface{}{"a": "Ny-Ålesund Research Station"}},
+		{"replace", `{"a": [{"op": "replace", "pattern": "\\s+", "with": "_"}]}`, map[string]interface{}{"a": "a  b c"}, map[string]interface{}{"a": "a_b_c"}},
+		{"split and join", `{"a": [{"op": "split", "separator": ";"}], "b": [{"op": "join", "separator": "-"}]}`, map[string]interface{}{"a": "x;y", "b": []interface{}{"1", 2.0}}, map[string]interface{}{"a": []interface{}{"x", "y"}, "b": "1-2"}},
+		{"round", `{"a": [{"op": "round", "digits": 2}]}`, map[string]interface{}{"a": 1.23456}, map[string]interface{}{"a": 1.23}},
+		{"scale and offset", `{"a": [{"op": "scale", "factor": 0.5}, {"op": "offset", "value": -200}]}`, map[string]interface{}{"a": 3000.0}, map[string]interface{}{"a": 1300.0}},
+		{"scale by zero", `{"a": [{"op": "scale", "factor": 0}]}`, map[string]interface{}{"a": 5.0}, map[string]interface{}{"a": 0.0}},
+		{"arrays are transformed per element", `{"a": [{"op": "upper"}]}`, map[string]interface{}{"a": []interface{}{"x", "y"}}, map[string]interface{}{"a": []interface{}{"X", "Y"}}},
+		{"default", `{"a": [{"op": "default", "value": "n/a"}], "b": [{"op": "default", "value": 1}]}`, map[string]interface{}{"b": 2.0}, map[string]interface{}{"a": "n/a", "b": 2.0}},
+		{"nested key path", `{"p.t": [{"op": "cast", "type": "number"}]}`, map[string]interface{}{"p": map[string]interface{}{"t": "7"}}, map[string]interface{}{"p": map[string]interface{}{"t": 7.0}}},
+		{"missing keys are left alone", `{"a": [{"op": "upper"}]}`, map[string]interface{}{"b": "x"}, map[string]interface{}{"b": "x"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr := NewTransformer(newTestContext(map[string]interface{}{"transform": test.spec}))
+			if tr.err != nil {
+				t.Fatal(tr.err)
+			}
+			got, err := tr.transform(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTransformSpecErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{`{"a": [{"op": "nope"}]}`, `a nope: Unknown operation`},
+		{`{"a": [{"op": "replace", "pattern": "("}]}`, `a replace: error parsing regexp`},
+		{`{"a": [{"op": "cast", "type": "date"}]}`, `a cast: Unknown type "date"`},
+		{`{"a": [{"op": "scale"}]}`, `a scale: needs a factor`},
+		{`{"a": [{"op": "offset"}]}`, `a offset: needs a numeric value`},
+		{`{"a": [{"op": "offset", "value": "1"}]}`, `a offset: needs a numeric value`},
+		{`{"a": `, ``},
+	}
+
+	for _, test := range tests {
+		tr := NewTransformer(newTestContext(map[string]interface{}{"transform": test.spec}))
+		if tr.err == nil || !strings.Contains(tr.err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.spec, tr.err, test.err)
+		}
+	}
+}
+
+func TestTransformValueErrors(t *testing.T) {
+	tr := NewTransformer(newTestContext(map[string]interface{}{"transform": `{"a": [{"op": "cast", "type": "float"}]}`}))
+	if _, err := tr.transform(map[string]interface{}{"a": "twelve"}); err == nil {
+		t.Error("expected an error for a value that is not a number")
+	}
+}
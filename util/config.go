@@ -0,0 +1,33 @@
+package util
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadConfig reads inline JSON, or a JSON or YAML file, into v. The result is
+// decoded with encoding/json so the json struct tags of v apply to both formats
+func ReadConfig(input string, v interface{}) error {
+	raw := []byte(input)
+	if trimmed := strings.TrimSpace(input); !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		var err error
+		if raw, err = ioutil.ReadFile(input); err != nil {
+			return err
+		}
+	}
+
+	// YAML is a superset of JSON so both formats are read with the yaml parser
+	var config interface{}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return err
+	}
+
+	converted, err := json.Marshal(config)
+	if err == nil {
+		err = json.Unmarshal(converted, v)
+	}
+	return err
+}
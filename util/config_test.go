@@ -0,0 +1,36 @@
+package util
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	type spec struct {
+		Keys     []string `json:"keys"`
+		DropNull bool     `json:"drop_null"`
+	}
+
+	file := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := ioutil.WriteFile(file, []byte("keys: [a, b]\ndrop_null: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got spec
+	if err := ReadConfig(file, &got); err != nil || !reflect.DeepEqual(got, spec{Keys: []string{"a", "b"}, DropNull: true}) {
+		t.Errorf("yaml file: got %+v, %v", got, err)
+	}
+
+	got = spec{}
+	if err := ReadConfig(` {"keys": ["a"]}`, &got); err != nil || !reflect.DeepEqual(got, spec{Keys: []string{"a"}}) {
+		t.Errorf("inline json: got %+v, %v", got, err)
+	}
+
+	for _, input := range []string{filepath.Join(t.TempDir(), "none.yaml"), `{"keys": "a"}`} {
+		if err := ReadConfig(input, &got); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
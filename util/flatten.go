@@ -0,0 +1,76 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flatten returns a copy of data with nested objects and arrays collapsed
+// into keys joined by sep. Example: {"a": {"b": [1]}} -> {"a.b.0": 1}.
+// Empty objects and arrays are kept as values.
+func Flatten(data map[string]interface{}, sep string) map[string]interface{} {
+	flat := make(map[string]interface{})
+	for key, value := range data {
+		flattenValue(flat, key, value, sep)
+	}
+	return flat
+}
+
+func flattenValue(flat map[string]interface{}, prefix string, value interface{}, sep string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			for key, child := range v {
+				flattenValue(flat, prefix+sep+key, child, sep)
+			}
+			return
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			for i, child := range v {
+				flattenValue(flat, prefix+sep+strconv.Itoa(i), child, sep)
+			}
+			return
+		}
+	}
+	flat[prefix] = value
+}
+
+// Unflatten returns a copy of data with keys containing sep expanded into
+// nested objects, or arrays for numeric segments. Keys with empty segments
+// are kept as they are.
+func Unflatten(data map[string]interface{}, sep string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	nested := make(map[string]interface{})
+	for _, key := range keys {
+		segments := strings.Split(key, sep)
+		if len(segments) == 1 || containsEmpty(segments) {
+			if _, exists := nested[key]; exists {
+				return nested, fmt.Errorf("Could not unflatten %s: key is used as an object", key)
+			}
+			nested[key] = data[key]
+			continue
+		}
+
+		if _, err := setSegments(nested, segments, data[key]); err != nil {
+			return nested, fmt.Errorf("Could not unflatten %s: %v", key, err)
+		}
+	}
+	return nested, nil
+}
+
+func containsEmpty(segments []string) bool {
+	for _, segment := range segments {
+		if segment == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	data := map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1.0, map[string]interface{}{"c": "x"}}}, "e": []interface{}{}}
+	flat := map[string]interface{}{"a_b_0": 1.0, "a_b_1_c": "x", "e": []interface{}{}}
+
+	if got := Flatten(data, "_"); !reflect.DeepEqual(got, flat) {
+		t.Errorf("Flatten: got %v, want %v", got, flat)
+	}
+	if got, err := Unflatten(flat, "_"); err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("Unflatten: got %v, %v, want %v", got, err, data)
+	}
+	if got, err := Unflatten(map[string]interface{}{".hidden": 1.0}, "."); err != nil || got[".hidden"] != 1.0 {
+		t.Errorf("empty segments should be kept: got %v, %v", got, err)
+	}
+	if _, err := Unflatten(map[string]interface{}{"a": 1.0, "a.b": 2.0}, "."); err == nil {
+		t.Error("expected an error for a value and an object on the same key")
+	}
+}
@@ -0,0 +1,270 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxArrayPadding is how far past the end of an array SetPath may set an index
+	maxArrayPadding = 1024
+)
+
+// removedElement marks array elements removed by DeletePaths until the arrays are compacted
+type removedElement struct{}
+
+// SplitPath splits a key path into its segments. Paths starting with "/" are
+// read as JSON Pointers (RFC 6901), anything else is split on ".".
+// Example: properties.station.id, data.0.value or /data/0/value
+func SplitPath(path string) []string {
+	if !strings.HasPrefix(path, "/") {
+		return strings.Split(path, ".")
+	}
+
+	segments := strings.Split(path[1:], "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+	}
+	return segments
+}
+
+// GetPath returns the value at path. A top level key matching the complete
+// path always wins, so keys containing dots keep working.
+func GetPath(data map[string]interface{}, path string) (interface{}, bool) {
+	if val, ok := data[path]; ok {
+		return val, true
+	}
+
+	var current interface{} = data
+	for _, segment := range SplitPath(path) {
+		var ok bool
+		if current, ok = getSegment(current, segment); !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// ExpandPath returns the segments of every value matching path, where a "*"
+// segment matches all elements of an array or values of an object. A named
+// segment meeting an array is an error, since the elements would be missed.
+func ExpandPath(data map[string]interface{}, path string) ([][]string, error) {
+	if _, ok := data[path]; ok {
+		return [][]string{{path}}, nil
+	}
+	return expandSegments(data, SplitPath(path), nil)
+}
+
+// JoinPath joins segments into a JSON Pointer, the inverse of SplitPath
+func JoinPath(segments []string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = strings.Replace(strings.Replace(segment, "~", "~0", -1), "/", "~1", -1)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+// SetPath sets the value at path and creates the missing objects (or arrays
+// for numeric segments) along the way
+func SetPath(data map[string]interface{}, path string, value interface{}) error {
+	if _, ok := data[path]; ok {
+		data[path] = value
+		return nil
+	}
+
+	_, err := setSegments(data, SplitPath(path), value)
+	if err != nil {
+		err = fmt.Errorf("Could not set %s: %v", path, err)
+	}
+	return err
+}
+
+// DeletePath removes the value at path and returns true if it was present.
+// Array elements are removed from the array.
+func DeletePath(data map[string]interface{}, path string) bool {
+	if _, ok := data[path]; ok {
+		delete(data, path)
+		return true
+	}
+
+	_, ok := removeSegments(data, SplitPath(path), false)
+	return ok
+}
+
+// DeletePaths removes the values at paths. Array indices refer to the
+// positions before any of the paths were removed, so "a.0" and "a.1"
+// remove the first two elements of a.
+func DeletePaths(data map[string]interface{}, paths []string) {
+	removed := false
+	for _, path := range paths {
+		if _, ok := data[path]; ok {
+			delete(data, path)
+			continue
+		}
+		_, ok := removeSegments(data, SplitPath(path), true)
+		removed = removed || ok
+	}
+	if removed {
+		compact(data)
+	}
+}
+
+func expandSegments(container interface{}, segments []string, prefix []string) ([][]string, error) {
+	if len(segments) == 0 {
+		return [][]string{prefix}, nil
+	}
+
+	segment := segments[0]
+	var children []string
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if segment != "*" {
+			children = []string{segment}
+		} else {
+			for key := range c {
+				children = append(children, key)
+			}
+			sort.Strings(children)
+		}
+	case []interface{}:
+		if segment == "*" {
+			for i := range c {
+				children = append(children, strconv.Itoa(i))
+			}
+		} else if _, err := strconv.Atoi(segment); err != nil {
+			return nil, fmt.Errorf("%s is an array, use * to reach its elements", JoinPath(prefix))
+		} else {
+			children = []string{segment}
+		}
+	}
+
+	var matches [][]string
+	for _, child := range children {
+		value, ok := getSegment(container, child)
+		if !ok {
+			continue
+		}
+		found, err := expandSegments(value, segments[1:], append(prefix[:len(prefix):len(prefix)], child))
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+	}
+	return matches, nil
+}
+
+func getSegment(container interface{}, segment string) (interface{}, bool) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		val, ok := c[segment]
+		return val, ok
+	case []interface{}:
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(c) {
+			return c[i], true
+		}
+	}
+	return nil, false
+}
+
+func setSegments(container interface{}, segments []string, value interface{}) (interface{}, error) {
+	segment := segments[0]
+	if container == nil {
+		if _, err := strconv.Atoi(segment); err == nil {
+			container = []interface{}{}
+		} else {
+			container = make(map[string]interface{})
+		}
+	}
+
+	var err error
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if len(segments) == 1 {
+			c[segment] = value
+		} else {
+			c[segment], err = setSegments(c[segment], segments[1:], value)
+		}
+		return c, err
+	case []interface{}:
+		i := len(c)
+		if segment != "-" {
+			if i, err = strconv.Atoi(segment); err != nil || i < 0 {
+				return c, fmt.Errorf("%s is not an array index", segment)
+			}
+		}
+		if i >= len(c)+maxArrayPadding {
+			return c, fmt.Errorf("array index %d is too far past the end of the array (length %d)", i, len(c))
+		}
+		for len(c) <= i {
+			c = append(c, nil)
+		}
+		if len(segments) == 1 {
+			c[i] = value
+		} else {
+			c[i], err = setSegments(c[i], segments[1:], value)
+		}
+		return c, err
+	default:
+		return c, fmt.Errorf("%s is not an object or array", segment)
+	}
+}
+
+// removeSegments deletes the value at segments. With mark array elements are
+// replaced by a removedElement instead, so the other indices keep their position
+func removeSegments(container interface{}, segments []string, mark bool) (interface{}, bool) {
+	segment := segments[0]
+
+	switch c := container.(type) {
+	case map[string]interface{}:
+		child, ok := c[segment]
+		if !ok {
+			return c, false
+		}
+		if len(segments) == 1 {
+			delete(c, segment)
+			return c, true
+		}
+		c[segment], ok = removeSegments(child, segments[1:], mark)
+		return c, ok
+	case []interface{}:
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= len(c) {
+			return c, false
+		}
+		if _, removed := c[i].(removedElement); removed {
+			return c, false
+		}
+		if len(segments) == 1 {
+			if mark {
+				c[i] = removedElement{}
+				return c, true
+			}
+			return append(c[:i], c[i+1:]...), true
+		}
+		var ok bool
+		c[i], ok = removeSegments(c[i], segments[1:], mark)
+		return c, ok
+	}
+	return container, false
+}
+
+// compact drops the marked elements from the arrays in value
+func compact(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			typed[key] = compact(child)
+		}
+	case []interface{}:
+		kept := typed[:0]
+		for _, child := range typed {
+			if _, removed := child.(removedElement); !removed {
+				kept = append(kept, compact(child))
+			}
+		}
+		return kept
+	}
+	return value
+}
@@ -0,0 +1,159 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"a", []string{"a"}},
+		{"properties.station.id", []string{"properties", "station", "id"}},
+		{"/data/0/value", []string{"data", "0", "value"}},
+		{"/a~1b/c~0d", []string{"a/b", "c~d"}},
+	}
+
+	for _, test := range tests {
+		if got := SplitPath(test.path); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitPath(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	data := map[string]interface{}{
+		"a.b": "top",
+		"a":   map[string]interface{}{"b": "nested", "c": []interface{}{1.0, map[string]interface{}{"d": "x"}}},
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+		ok   bool
+	}{
+		{"a.b", "top", true},
+		{"/a/b", "nested", true},
+		{"a.c.0", 1.0, true},
+		{"a.c.1.d", "x", true},
+		{"a.c.2", nil, false},
+		{"a.c.-1", nil, false},
+		{"a.x", nil, false},
+		{"a.b.c", nil, false},
+	}
+
+	for _, test := range tests {
+		got, ok := GetPath(data, test.path)
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GetPath(%q) = %v, %v, want %v, %v", test.path, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		path string
+		want map[string]interface{}
+		err  string
+	}{
+		{"top level", map[string]interface{}{}, "a", map[string]interface{}{"a": "v"}, ""},
+		{"creates objects", map[string]interface{}{}, "a.b", map[string]interface{}{"a": map[string]interface{}{"b": "v"}}, ""},
+		{"creates arrays", map[string]interface{}{}, "a.1", map[string]interface{}{"a": []interface{}{nil, "v"}}, ""},
+		{"appends", map[string]interface{}{"a": []interface{}{"x"}}, "/a/-", map[string]interface{}{"a": []interface{}{"x", "v"}}, ""},
+		{"existing dotted key", map[string]interface{}{"a.b": 1.0}, "a.b", map[string]interface{}{"a.b": "v"}, ""},
+		{"not a container", map[string]interface{}{"a": "x"}, "a.b", nil, "not an object or array"},
+		{"not an index", map[string]interface{}{"a": []interface{}{}}, "a.b", nil, "not an array index"},
+		{"index too far", map[string]interface{}{}, "a.1000000000", nil, "too far past the end"},
+	}
+
+	for _, test := range tests {
+		err := SetPath(test.data, test.path, "v")
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if !reflect.DeepEqual(test.data, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, test.data, test.want)
+		}
+	}
+}
+
+func TestDeletePaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  map[string]interface{}
+	}{
+		{"keys", []string{"a", "b.c"}, map[string]interface{}{"b": map[string]interface{}{}, "list": []interface{}{"0", "1", "2", "3"}}},
+		{"indices in order", []string{"list.0", "list.1"}, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}, "list": []interface{}{"2", "3"}}},
+		{"indices in reverse", []string{"list.3", "list.1"}, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}, "list": []interface{}{"0", "2"}}},
+		{"same index twice", []string{"list.1", "/list/1"}, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}, "list": []interface{}{"0", "2", "3"}}},
+		{"missing paths", []string{"x", "list.9"}, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}, "list": []interface{}{"0", "1", "2", "3"}}},
+	}
+
+	for _, test := range tests {
+		data := map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}, "list": []interface{}{"0", "1", "2", "3"}}
+		DeletePaths(data, test.paths)
+		if !reflect.DeepEqual(data, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, data, test.want)
+		}
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	data := map[string]interface{}{"list": []interface{}{"0", "1"}}
+	if !DeletePath(data, "list.0") || DeletePath(data, "list.1") {
+		t.Error("the second element should have moved to index 0")
+	}
+	if !reflect.DeepEqual(data, map[string]interface{}{"list": []interface{}{"1"}}) {
+		t.Errorf("got %v", data)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	data := map[string]interface{}{
+		"a.b":  1.0,
+		"rows": []interface{}{map[string]interface{}{"email": "x"}, map[string]interface{}{"name": "y"}, map[string]interface{}{"email": "z"}},
+		"obj":  map[string]interface{}{"k2": map[string]interface{}{"v": 2.0}, "k1": map[string]interface{}{"v": 1.0}},
+	}
+
+	tests := []struct {
+		path string
+		want [][]string
+		err  bool
+	}{
+		{"a.b", [][]string{{"a.b"}}, false},
+		{"rows.*.email", [][]string{{"rows", "0", "email"}, {"rows", "2", "email"}}, false},
+		{"rows.1.name", [][]string{{"rows", "1", "name"}}, false},
+		{"obj.*.v", [][]string{{"obj", "k1", "v"}, {"obj", "k2", "v"}}, false},
+		{"missing.*", nil, false},
+		{"rows.email", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ExpandPath(data, test.path)
+		if (err != nil) != test.err {
+			t.Errorf("ExpandPath(%q): got error %v, want error %v", test.path, err, test.err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ExpandPath(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := [][]string{{"a"}, {"a.b", "0"}, {"a/b", "c~d"}}
+	for _, segments := range tests {
+		if got := SplitPath(JoinPath(segments)); !reflect.DeepEqual(got, segments) {
+			t.Errorf("SplitPath(JoinPath(%v)) = %v", segments, got)
+		}
+	}
+}
@@ -10,19 +10,17 @@ import (
 // Validator typedef
 type Validator struct {
 	context context.GhostContext
-	schema  gojsonschema.JSONLoader
+	schema  *gojsonschema.Schema
+	err     error
 }
 
-// NewValidator factory
+// NewValidator factory. The schema is loaded and compiled once
 func NewValidator(c context.GhostContext) *Validator {
-	var jsonRef gojsonschema.JSONLoader
+	v := &Validator{context: c}
 	if s := c.GlobalString("schema"); s != "" {
-		jsonRef = gojsonschema.NewReferenceLoader(s)
-	}
-	return &Validator{
-		context: c,
-		schema:  jsonRef,
+		v.schema, v.err = gojsonschema.NewSchema(gojsonschema.NewReferenceLoader(s))
 	}
+	return v
 }
 
 /*
@@ -30,12 +28,14 @@ func NewValidator(c context.GhostContext) *Validator {
 */
 func (v *Validator) validate(data map[string]interface{}) error {
 	var err error
+	if v.err != nil {
+		return fmt.Errorf("[Validation error] %v", v.err)
+	}
 	if v.schema != nil {
 		var result *gojsonschema.Result
-		schemaLoader := v.schema
 		documentLoader := gojsonschema.NewGoLoader(data)
 
-		if result, err = gojsonschema.Validate(schemaLoader, documentLoader); err == nil {
+		if result, err = v.schema.Validate(documentLoader); err == nil {
 			if !result.Valid() {
 				first := result.Errors()[0]
 				err = fmt.Errorf("[Validation error] %v: %v (was %v)", first.Field(), first.Description(), first.Value())
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -25,49 +26,153 @@ const (
 
 type mapper func(data map[string]interface{}) (map[string]interface{}, error)
 
+// metaMapper is a mapper that also reads the metadata of the document
+type metaMapper func(data map[string]interface{}, meta *docMeta) (map[string]interface{}, error)
+
 type dataFile struct {
-	name string
-	data map[string]interface{}
+	name   string
+	data   map[string]interface{}
+	source *rawFile
+	row    int
+}
+
+// docMeta holds what is known about the origin of a document
+type docMeta struct {
+	name     string
+	captures []string
+	source   *rawFile
+	row      int
 }
 
 // Writer type definition
 type Writer struct {
-	context   context.GhostContext
-	dataChan  chan *dataFile
-	Js        *Js
-	Validator *Validator
+	context     context.GhostContext
+	dataChan    chan *dataFile
+	Js          *Js
+	Validator   *Validator
+	Transformer *Transformer
+	Dates       *DateNormalizer
+	Missing     *MissingValues
+	Filter      *Filter
+	Lookup      *Lookup
+	Geometry    *GeometryBuilder
+	Reprojector *Reprojector
+	Spatial     *SpatialFilter
+	Templater   *Templater
+	Privacy     *Privacy
+	Grouper     *Grouper
+	Reshaper    *Reshaper
+	Tracker     *Tracker
+	QC          *QualityControl
+	pipeline    []metaMapper
+	validates   bool
+	summary     runSummary
 }
 
 // NewWriter initialises a new Writer and return a pointer to it
 func NewWriter(c context.GhostContext, dc chan *dataFile) *Writer {
-	return &Writer{
-		context:   c,
-		dataChan:  dc,
-		Js:        NewJs(c),
-		Validator: NewValidator(c),
+	w := &Writer{
+		context:     c,
+		dataChan:    dc,
+		Js:          NewJs(c),
+		Validator:   NewValidator(c),
+		Transformer: NewTransformer(c),
+		Dates:       NewDateNormalizer(c),
+		Missing:     NewMissingValues(c),
+		Filter:      NewFilter(c),
+		Lookup:      NewLookup(c),
+		Geometry:    NewGeometryBuilder(c),
+		Reprojector: NewReprojector(c),
+		Spatial:     NewSpatialFilter(c),
+		Templater:   NewTemplater(c),
+		Privacy:     NewPrivacy(c),
+		Grouper:     NewGrouper(c),
+		Reshaper:    NewReshaper(c),
+		Tracker:     NewTracker(c),
+		QC:          NewQualityControl(c),
+	}
+
+	// The qc stage runs before the mappers, it reads the missing sentinels itself
+	w.QC.missing = w.Missing
+	if keyMap := c.GlobalString("key-map"); keyMap != "" {
+		w.QC.keyMap, _ = w.readData(keyMap)
 	}
+	return w
+}
+
+// newStepWriter initialises a Writer that is only used for the mapper of a
+// pipeline step. It shares the components of w and only loads the component
+// of the named step again with the step context
+func (w *Writer) newStepWriter(c context.GhostContext, name string) (*Writer, error) {
+	step := *w
+	step.context = c
+	step.dataChan = nil
+	step.pipeline = nil
+
+	switch name {
+	case "where":
+		step.Filter = NewFilter(c)
+	case "missing":
+		step.Missing = NewMissingValues(c)
+	case "transform":
+		step.Transformer = NewTransformer(c)
+	case "dates":
+		step.Dates = NewDateNormalizer(c)
+	case "lookup":
+		step.Lookup = NewLookup(c)
+	case "geometry":
+		step.Geometry = NewGeometryBuilder(c)
+	case "within":
+		step.Spatial = NewSpatialFilter(c)
+	case "reproject":
+		step.Reprojector = NewReprojector(c)
+	case "privacy":
+		step.Privacy = NewPrivacy(c)
+	case "template":
+		step.Templater = NewTemplater(c)
+	case "js":
+		step.Js = NewJs(c)
+	case "validate":
+		step.Validator = NewValidator(c)
+	}
+
+	return &step, step.check()
 }
 
 // Listens to the dataChan and applies the configured output modifiers and then writes
 // the result to the configured output channel [stdout|files|http]
 func (w *Writer) listen() (*sync.WaitGroup, error) {
-	err := w.createOutputDir()
+	if err := w.check(); err != nil {
+		return nil, err
+	}
+
+	if err := w.buildPipeline(); err != nil {
+		return nil, err
+	}
+
+	stages, err := w.stages()
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.createOutputDir()
 	sem := make(chan int, w.context.GlobalInt("concurrency"))
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		for data := range w.dataChan {
+		for data := range runStages(w.dataChan, stages) {
 			sem <- 1
 			wg.Add(1)
-			go func(dataMap map[string]interface{}, name string) {
-				dataMap, err = w.parseFileName(name, dataMap)
+			go func(dataMap map[string]interface{}, meta *docMeta) {
+				w.summary.count(&w.summary.documents)
+				dataMap, err := w.parseFileName(meta, dataMap)
 
 				if err == nil {
-					dataMap, err = w.applyMappers(dataMap)
+					dataMap, err = w.applyMappers(dataMap, meta)
 				}
 
 				if dataMap != nil {
-					if err == nil {
+					if err == nil && !w.validates {
 						err = w.Validator.validate(dataMap)
 					}
 					if err == nil {
@@ -75,12 +180,18 @@ func (w *Writer) listen() (*sync.WaitGroup, error) {
 					}
 				}
 
-				if err != nil {
+				switch {
+				case err != nil:
+					w.summary.count(&w.summary.failed)
 					log.Error(err.Error())
+				case dataMap == nil:
+					w.summary.count(&w.summary.dropped)
+				default:
+					w.summary.count(&w.summary.published)
 				}
 				<-sem
 				wg.Done()
-			}(data.data, data.name)
+			}(data.data, &docMeta{name: data.name, source: data.source, row: data.row})
 		}
 		wg.Done()
 	}()
@@ -88,30 +199,116 @@ func (w *Writer) listen() (*sync.WaitGroup, error) {
 	return &wg, err
 }
 
-func (w *Writer) applyMappers(dataMap map[string]interface{}) (map[string]interface{}, error) {
+// buildPipeline sets up the mappers in the order of the --pipeline file or the default order.
+// A pipeline with a validate step replaces the validation after the last mapper
+func (w *Writer) buildPipeline() error {
+	pipeline, err := NewPipeline(w.context)
+	if err == nil {
+		w.pipeline, err = pipeline.mappers(w)
+		w.validates = pipeline.has("validate")
+	}
+	return err
+}
+
+// check returns the first configuration error of the mapper components so a
+// bad configuration stops the run at startup instead of failing every document
+func (w *Writer) check() error {
+	components := []struct {
+		name string
+		err  error
+	}{
+		{"where", w.Filter.err},
+		{"missing", w.Missing.err},
+		{"transform", w.Transformer.err},
+		{"dates", w.Dates.err},
+		{"lookup", w.Lookup.err},
+		{"geometry", w.Geometry.err},
+		{"within", w.Spatial.err},
+		{"reproject", w.Reprojector.err},
+		{"privacy", w.Privacy.err},
+		{"template", w.Templater.err},
+		{"schema", w.Validator.err},
+	}
+
+	for _, component := range components {
+		if component.err != nil {
+			return fmt.Errorf("[Config Error] %s: %v", component.name, component.err)
+		}
+	}
+	return nil
+}
+
+// stages returns the sequential stages in the order they run
+func (w *Writer) stages() ([]stage, error) {
+	for _, err := range []error{w.Tracker.err, w.QC.err, w.Reshaper.err, w.Grouper.err} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []stage{w.Tracker.stage, w.QC.stage, w.Reshaper.stage, w.Grouper.stage}, nil
+}
+
+// mapper returns the mapper registered under name in a pipeline
+func (w *Writer) mapper(name string) (metaMapper, bool) {
+	metaMappers := map[string]metaMapper{
+		"merge":      w.mergeData,
+		"wrap":       w.wrapData,
+		"provenance": w.injectProvenance,
+	}
+	if fn, ok := metaMappers[name]; ok {
+		return fn, true
+	}
+
+	mappers := map[string]mapper{
+		"where":     w.Filter.filter,
+		"include":   w.includeKeys,
+		"exclude":   w.excludeKeys,
+		"key-map":   w.mapKeys,
+		"unflatten": w.unflattenKeys,
+		"missing":   w.Missing.normalize,
+		"transform": w.Transformer.transform,
+		"dates":     w.Dates.normalize,
+		"lookup":    w.Lookup.enrich,
+		"geometry":  w.Geometry.build,
+		"within":    w.Spatial.filter,
+		"reproject": w.Reprojector.reproject,
+		"privacy":   w.Privacy.mask,
+		"template":  w.Templater.apply,
+		"flatten":   w.flattenKeys,
+		"uuid":      w.injectUUID,
+		"js":        w.runJs,
+		"validate":  w.validate,
+	}
+	fn, ok := mappers[name]
+	if !ok {
+		return nil, false
+	}
+	return func(data map[string]interface{}, _ *docMeta) (map[string]interface{}, error) {
+		return fn(data)
+	}, true
+}
+
+func (w *Writer) applyMappers(dataMap map[string]interface{}, meta *docMeta) (map[string]interface{}, error) {
 	var err error
-	mappers := []mapper{
-		w.includeKeys,
-		w.excludeKeys,
-		w.mapKeys,
-		w.mergeData,
-		w.wrapData,
-		w.injectUUID,
-		w.runJs}
-
-	for _, fn := range mappers {
-		dataMap, err = fn(dataMap)
+
+	for _, fn := range w.pipeline {
+		dataMap, err = fn(dataMap, meta)
 		if err != nil {
 			err = errors.New("[Writer error] " + err.Error())
 			break
 		}
+		if dataMap == nil {
+			break
+		}
 	}
 	return dataMap, err
 }
 
 // ParseFileName handles meta data extraction from filenames. It reads the pattern
-// file specified with the --name-pattern argument and parses the filename according
-func (w *Writer) parseFileName(fname string, dataMap map[string]interface{}) (map[string]interface{}, error) {
+// file specified with the --name-pattern argument and parses the filename according.
+// The captures are kept in the document metadata for the wrapper and merge placeholders
+func (w *Writer) parseFileName(meta *docMeta, dataMap map[string]interface{}) (map[string]interface{}, error) {
+	fname := meta.name
 	var err error
 	if pat := w.context.GlobalString("name-pattern"); pat != "" {
 		var pattern = make(map[string]interface{})
@@ -123,6 +320,7 @@ func (w *Writer) parseFileName(fname string, dataMap map[string]interface{}) (ma
 		if err = json.Unmarshal(pdoc, &pattern); err == nil {
 			if pRgx := regexp.MustCompile(pattern["pattern"].(string)); pRgx.MatchString(fname) {
 				matches := pRgx.FindStringSubmatch(fname)
+				meta.captures = matches
 				outputB, _ := json.Marshal(pattern["output"])
 				output := string(outputB)
 
@@ -158,6 +356,11 @@ func (w *Writer) runJs(data map[string]interface{}) (map[string]interface{}, err
 	return dataMap, err
 }
 
+// validate runs the schema validation as a pipeline step
+func (w *Writer) validate(data map[string]interface{}) (map[string]interface{}, error) {
+	return data, w.Validator.validate(data)
+}
+
 func (w *Writer) includeKeys(data map[string]interface{}) (map[string]interface{}, error) {
 	var err error
 	includeData := data
@@ -166,26 +369,54 @@ func (w *Writer) includeKeys(data map[string]interface{}) (map[string]interface{
 		includesSlice := util.StringToSlice(includes)
 		includeData = make(map[string]interface{})
 		for _, key := range includesSlice {
-			includeData[key] = data[key]
+			if val, ok := data[key]; ok {
+				includeData[key] = val
+				continue
+			}
+			// keys that are not found are included as null
+			val, _ := util.GetPath(data, key)
+			if err = util.SetPath(includeData, key, val); err != nil {
+				err = errors.New("includeKeys: " + err.Error())
+				break
+			}
 		}
 	}
 
 	return includeData, err
 }
 
+// flattenKeys collapses nested objects and arrays into keys joined by the --flatten separator
+func (w *Writer) flattenKeys(data map[string]interface{}) (map[string]interface{}, error) {
+	if sep := w.context.GlobalString("flatten"); sep != "" {
+		return util.Flatten(data, sep), nil
+	}
+	return data, nil
+}
+
+// unflattenKeys expands keys joined by the --unflatten separator into nested objects
+func (w *Writer) unflattenKeys(data map[string]interface{}) (map[string]interface{}, error) {
+	if sep := w.context.GlobalString("unflatten"); sep != "" {
+		nested, err := util.Unflatten(data, sep)
+		if err != nil {
+			return data, errors.New("unflattenKeys: " + err.Error())
+		}
+		return nested, nil
+	}
+	return data, nil
+}
+
 func (w *Writer) excludeKeys(data map[string]interface{}) (map[string]interface{}, error) {
 	var err error
 
 	if excludes := w.context.GlobalString("exclude"); excludes != "" {
-		excludesSlice := util.StringToSlice(excludes)
-		for _, key := range excludesSlice {
-			delete(data, key)
-		}
+		util.DeletePaths(data, util.StringToSlice(excludes))
 	}
 
 	return data, err
 }
 
+// mapKeys renames keys according to the key-map. Both sides of the mapping
+// can be key paths, missing objects in the target path are created
 func (w *Writer) mapKeys(data map[string]interface{}) (map[string]interface{}, error) {
 	var err error
 	dataMap := data
@@ -193,8 +424,11 @@ func (w *Writer) mapKeys(data map[string]interface{}) (map[string]interface{}, e
 	if keyMap := w.context.GlobalString("key-map"); keyMap != "" {
 		if mapping, mapErr := w.readData(keyMap); mapErr == nil {
 			for key, val := range mapping {
-				dataMap[val.(string)] = dataMap[key]
-				delete(dataMap, key)
+				value, _ := util.GetPath(dataMap, key)
+				util.DeletePath(dataMap, key)
+				if setErr := util.SetPath(dataMap, val.(string), value); setErr != nil && err == nil {
+					err = errors.New("mapKeys: " + setErr.Error())
+				}
 			}
 		} else {
 			err = errors.New("mapKeys: " + mapErr.Error())
@@ -204,11 +438,15 @@ func (w *Writer) mapKeys(data map[string]interface{}) (map[string]interface{}, e
 	return dataMap, err
 }
 
-func (w *Writer) wrapData(data map[string]interface{}) (map[string]interface{}, error) {
+func (w *Writer) wrapData(data map[string]interface{}, meta *docMeta) (map[string]interface{}, error) {
 	var err error
 
 	if wrap := w.context.GlobalString("wrapper"); wrap != "" {
-		if wrapper, dataErr := w.readData(wrap); dataErr == nil {
+		wrapper, dataErr := w.readData(wrap)
+		if dataErr == nil {
+			_, dataErr = resolvePlaceholders(w.context, wrapper, data, meta)
+		}
+		if dataErr == nil {
 			key := w.context.GlobalString("payload-key")
 			wrapper[key] = data
 			data = wrapper
@@ -220,11 +458,15 @@ func (w *Writer) wrapData(data map[string]interface{}) (map[string]interface{},
 	return data, err
 }
 
-func (w *Writer) mergeData(data map[string]interface{}) (map[string]interface{}, error) {
+func (w *Writer) mergeData(data map[string]interface{}, meta *docMeta) (map[string]interface{}, error) {
 	var err error
 
 	if merge := w.context.GlobalString("merge"); merge != "" {
-		if padding, dataErr := w.readData(merge); dataErr == nil {
+		padding, dataErr := w.readData(merge)
+		if dataErr == nil {
+			_, dataErr = resolvePlaceholders(w.context, padding, data, meta)
+		}
+		if dataErr == nil {
 
 			for key, val := range padding {
 				data[key] = val
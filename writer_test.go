@@ -0,0 +1,56 @@
+package ghostdoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIncludeExcludeKeys(t *testing.T) {
+	doc := func() map[string]interface{} {
+		return map[string]interface{}{
+			"a":    1.0,
+			"b":    map[string]interface{}{"c": 2.0, "d": 3.0},
+			"list": []interface{}{"0", "1", "2"},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		flags map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{"include", map[string]interface{}{"include": "a,b.c"}, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0}}},
+		{"include sets missing paths to null", map[string]interface{}{"include": "a,x,b.x"}, map[string]interface{}{"a": 1.0, "x": nil, "b": map[string]interface{}{"x": nil}}},
+		{"exclude", map[string]interface{}{"exclude": "a,b.c"}, map[string]interface{}{"b": map[string]interface{}{"d": 3.0}, "list": []interface{}{"0", "1", "2"}}},
+		{"exclude array elements", map[string]interface{}{"exclude": "list.0,list.1"}, map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"c": 2.0, "d": 3.0}, "list": []interface{}{"2"}}},
+	}
+
+	for _, test := range tests {
+		w := &Writer{context: newTestContext(test.flags)}
+		got, err := w.includeKeys(doc())
+		if err == nil {
+			got, err = w.excludeKeys(got)
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	w := &Writer{context: newTestContext(map[string]interface{}{"key-map": `{"IridLat": "position.lat", "IridLng": "position.lon"}`})}
+	got, err := w.mapKeys(map[string]interface{}{"IridLat": 78.2, "IridLng": 15.6, "id": "x"})
+	want := map[string]interface{}{"position": map[string]interface{}{"lat": 78.2, "lon": 15.6}, "id": "x"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestMapKeysError(t *testing.T) {
+	w := &Writer{context: newTestContext(map[string]interface{}{"key-map": `{"IridLat": "id.lat", "IridLng": "position.lon"}`})}
+	if _, err := w.mapKeys(map[string]interface{}{"IridLat": 78.2, "IridLng": 15.6, "id": "x"}); err == nil {
+		t.Error("expected an error for a target inside a string")
+	}
+}